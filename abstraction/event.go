@@ -1,10 +1,15 @@
 // Package abstraction must contain only interfaces and abstract layers of modules
 package abstraction
 
-import "github.com/maniartech/signals"
+import (
+	"context"
 
+	"github.com/maniartech/signals"
+)
+
+// EventGenerator emits events into the dispatcher until the context is canceled.
 type EventGenerator interface {
-	BuildTickChannel(EventDispatcher)
+	BuildTickChannel(context.Context, EventDispatcher)
 }
 
 type (
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fmotalleb/go-tools/defaulter"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/jobs"
+)
+
+// reloader re-reads the config file and applies its jobs to the running manager.
+type reloader struct {
+	mu      sync.Mutex
+	log     *zap.Logger
+	manager *jobs.Manager
+}
+
+func newReloader(manager *jobs.Manager) *reloader {
+	return &reloader{
+		log:     global.Logger("Reload"),
+		manager: manager,
+	}
+}
+
+// reload applies jobs of the new config, the new config is rejected (keeping the running one) if it is invalid.
+// Settings other than jobs are only applied on restart.
+func (r *reloader) reload() (jobs.Changes, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log.Info("reloading config")
+	cfg, err := loadConfig()
+	if err != nil {
+		r.log.Error("new config rejected, keeping the running config", zap.Error(err))
+		return jobs.Changes{}, err
+	}
+	if !sameSettings(CFG, cfg) {
+		r.log.Warn("changes to settings other than jobs are ignored until restart")
+	}
+	changes, err := r.manager.Apply(cfg.Jobs)
+	if err != nil {
+		r.log.Error("cannot apply jobs of the new config", zap.Error(err))
+		return jobs.Changes{}, err
+	}
+	CFG.Jobs = cfg.Jobs
+	return changes, nil
+}
+
+// sameSettings reports whether both configs are equal, ignoring their jobs.
+func sameSettings(a, b *config.Config) bool {
+	encode := func(cfg *config.Config) string {
+		settings := *cfg
+		settings.Jobs = nil
+		encoded, _ := json.Marshal(settings)
+		return string(encoded)
+	}
+	return encode(a) == encode(b)
+}
+
+// watch reloads the config on SIGHUP, and on changes of the config file if `watch_config` is set.
+func (r *reloader) watch() {
+	ctx := global.CTX()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				r.log.Info("received SIGHUP")
+				_, _ = r.reload()
+			}
+		}
+	}()
+	if CFG.WatchConfig {
+		viper.OnConfigChange(func(e fsnotify.Event) {
+			r.log.Info("config file changed", zap.String("file", e.Name))
+			_, _ = r.reload()
+		})
+		viper.WatchConfig()
+	}
+}
+
+// loadConfig reads, validates and applies defaults to the config file.
+func loadConfig() (*config.Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("cannot read the config file: %w", err)
+	}
+	cfg := &config.Config{}
+	if err := viper.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal the config file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+	defaulter.ApplyDefaults(cfg, cfg)
+	return cfg, nil
+}
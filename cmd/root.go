@@ -6,7 +6,6 @@ import (
 	"os"
 	"runtime"
 
-	"github.com/fmotalleb/go-tools/defaulter"
 	"github.com/fmotalleb/go-tools/env"
 	"github.com/fmotalleb/go-tools/git"
 	"github.com/fmotalleb/go-tools/log"
@@ -17,8 +16,12 @@ import (
 
 	"github.com/fmotalleb/crontab-go/cmd/parser"
 	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/discovery"
 	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/history"
 	"github.com/fmotalleb/crontab-go/core/jobs"
+	"github.com/fmotalleb/crontab-go/core/process"
 	"github.com/fmotalleb/crontab-go/core/webserver"
 )
 
@@ -48,7 +51,33 @@ within your containerized applications.`,
 		cronInstance.Start()
 		l := global.Logger("cron")
 		l.Info("Booting up")
-		jobs.InitializeJobs(CFG.Jobs)
+		if CFG.StateDir != "" {
+			store, err := history.Open(CFG.StateDir, CFG.HistoryMaxRuns, CFG.HistoryMaxOutput)
+			panicOnErr(err, "Cannot open the run history")
+			defer func() {
+				warnOnErr(store.Close(), "Cannot close the run history")
+			}()
+			global.Put(store)
+		}
+		if CFG.TaskMaxOutput != 0 {
+			global.Put(common.MaxOutput(CFG.TaskMaxOutput))
+		}
+		if CFG.StopSignal != "" {
+			stopSignal, err := process.ParseSignal(CFG.StopSignal)
+			panicOnErr(err, "Invalid stop signal")
+			global.Put(process.StopSignal(stopSignal))
+		}
+		if reapOrphans(CFG.InitMode) {
+			go process.Reap(global.CTX(), global.Logger("Reaper"))
+		}
+		manager := jobs.InitializeJobs(CFG.Jobs)
+		r := newReloader(manager)
+		r.watch()
+		if CFG.DockerDiscovery != "" {
+			go discovery.
+				NewDocker(CFG.DockerDiscovery, CFG.DockerDiscoveryPrefix, manager, global.Logger("Discovery")).
+				Run(global.CTX())
+		}
 		if CFG.WebServerAddress != "" {
 			go webserver.
 				NewWebServer(
@@ -62,9 +91,13 @@ within your containerized applications.`,
 						Password: CFG.WebServerPassword,
 					},
 				).
+				WithReloader(r.reload).
 				Serve()
 		}
 		<-global.CTX().Done()
+		l.Info("Shutting down")
+		cronInstance.Stop()
+		manager.Shutdown(CFG.ShutdownGrace)
 	},
 }
 
@@ -104,6 +137,18 @@ func init() {
 	// cobra.OnInitialize()
 }
 
+// reapOrphans reports whether orphaned processes should be reaped, by default only PID 1 (container init) reaps them.
+func reapOrphans(mode config.InitMode) bool {
+	switch mode {
+	case config.InitModeEnabled:
+		return true
+	case config.InitModeDisabled:
+		return false
+	default:
+		return os.Getpid() == 1
+	}
+}
+
 func warnOnErr(err error, message string) {
 	if err != nil {
 		fmt.Printf("%s, %v", message, err)
@@ -134,19 +179,9 @@ func initConfig() {
 		viper.SetConfigType("yaml")
 	}
 
-	panicOnErr(
-		viper.ReadInConfig(),
-		"Cannot read the config file: %s",
-	)
-	panicOnErr(
-		viper.Unmarshal(CFG),
-		"Cannot unmarshal the config file: %s",
-	)
-	panicOnErr(
-		CFG.Validate(),
-		"Failed to initialize config file: %s",
-	)
-	defaulter.ApplyDefaults(CFG, CFG)
+	cfg, err := loadConfig()
+	panicOnErr(err, "Cannot load the config")
+	CFG = cfg
 }
 
 func setupEnv() {
@@ -189,6 +224,71 @@ func setupEnv() {
 		"Cannot bind webserver_username env variable: %s",
 	)
 
+	warnOnErr(
+		viper.BindEnv(
+			"state_dir",
+		),
+		"Cannot bind state_dir env variable: %s",
+	)
+	warnOnErr(
+		viper.BindEnv(
+			"history_max_runs",
+		),
+		"Cannot bind history_max_runs env variable: %s",
+	)
+	warnOnErr(
+		viper.BindEnv(
+			"history_max_output",
+		),
+		"Cannot bind history_max_output env variable: %s",
+	)
+	warnOnErr(
+		viper.BindEnv(
+			"task_max_output",
+		),
+		"Cannot bind task_max_output env variable: %s",
+	)
+
+	warnOnErr(
+		viper.BindEnv(
+			"shutdown_grace",
+		),
+		"Cannot bind shutdown_grace env variable: %s",
+	)
+	warnOnErr(
+		viper.BindEnv(
+			"stop_signal",
+		),
+		"Cannot bind stop_signal env variable: %s",
+	)
+
+	warnOnErr(
+		viper.BindEnv(
+			"init_mode",
+		),
+		"Cannot bind init_mode env variable: %s",
+	)
+
+	warnOnErr(
+		viper.BindEnv(
+			"watch_config",
+		),
+		"Cannot bind watch_config env variable: %s",
+	)
+
+	warnOnErr(
+		viper.BindEnv(
+			"docker_discovery",
+		),
+		"Cannot bind docker_discovery env variable: %s",
+	)
+	warnOnErr(
+		viper.BindEnv(
+			"docker_discovery_prefix",
+		),
+		"Cannot bind docker_discovery_prefix env variable: %s",
+	)
+
 	warnOnErr(
 		viper.BindEnv(
 			"shell",
@@ -18,6 +18,28 @@ type Config struct {
 	WebServerPassword string `mapstructure:"webserver_password" json:"webserver_password,omitempty"`
 	WebServerMetrics  bool   `mapstructure:"webserver_metrics" json:"webserver_metrics,omitempty"`
 
+	// State config, run history is recorded only if the state directory is set
+	StateDir         string `mapstructure:"state_dir" json:"state_dir,omitempty"`
+	HistoryMaxRuns   uint   `mapstructure:"history_max_runs" json:"history_max_runs,omitempty"`
+	HistoryMaxOutput uint   `mapstructure:"history_max_output" json:"history_max_output,omitempty"`
+
+	// Output config, output and response bodies of tasks are captured up to this amount of bytes
+	TaskMaxOutput uint `mapstructure:"task_max_output" json:"task_max_output,omitempty"`
+
+	// Shutdown config, running tasks are given the grace period to finish before the stop signal is sent to them
+	ShutdownGrace time.Duration `mapstructure:"shutdown_grace" json:"shutdown_grace,omitempty"`
+	StopSignal    string        `mapstructure:"stop_signal" json:"stop_signal,omitempty"`
+
+	// Init config, orphaned processes are reaped in init mode
+	InitMode InitMode `mapstructure:"init_mode" json:"init_mode,omitempty"`
+
+	// Reload config, the config file is watched and reloaded on change if set
+	WatchConfig bool `mapstructure:"watch_config" json:"watch_config,omitempty"`
+
+	// Discovery config, jobs are discovered from labels of running containers if the docker connection is set
+	DockerDiscovery       string `mapstructure:"docker_discovery" json:"docker_discovery,omitempty"`
+	DockerDiscoveryPrefix string `mapstructure:"docker_discovery_prefix" json:"docker_discovery_prefix,omitempty"`
+
 	Jobs []*JobConfig `mapstructure:"jobs" json:"jobs"`
 }
 
@@ -27,10 +49,22 @@ type JobConfig struct {
 	Description string        `mapstructure:"description" json:"description,omitempty"`
 	Disabled    bool          `mapstructure:"disabled" json:"disabled,omitempty"`
 	Concurrency uint          `mapstructure:"concurrency" json:"concurrency,omitempty"`
+	Mode        JobMode       `mapstructure:"mode" json:"mode,omitempty"`
+	Overlap     OverlapPolicy `mapstructure:"overlap" json:"overlap,omitempty"`
+	MaxQueue    int           `mapstructure:"max-queue" json:"max-queue,omitempty"`
 	Tasks       []Task        `mapstructure:"tasks" json:"tasks,omitempty"`
 	Events      []JobEvent    `mapstructure:"events" json:"events"`
 	Hooks       JobHooks      `mapstructure:"hooks" json:"hooks,omitempty"`
 	Debounce    time.Duration `mapstructure:"debounce" json:"debounce,omitempty"`
+
+	// CatchUp replays cron ticks missed while the application was down (requires state_dir)
+	CatchUp         CatchUpPolicy `mapstructure:"catch-up" json:"catch-up,omitempty"`
+	CatchUpLookback time.Duration `mapstructure:"catch-up-lookback" json:"catch-up-lookback,omitempty"`
+}
+
+// CatchesUp reports whether missed cron ticks of the job are replayed.
+func (c *JobConfig) CatchesUp() bool {
+	return c.CatchUp != "" && c.CatchUp != CatchUpNone
 }
 
 // JobEvent represents the scheduling configuration for a job.
@@ -40,11 +74,28 @@ type JobEvent struct {
 	OnInit   bool          `mapstructure:"on-init" json:"on-init,omitempty"`
 	WebEvent string        `mapstructure:"web-event" json:"web-event,omitempty"`
 	Docker   *DockerEvent  `mapstructure:"docker" json:"docker,omitempty"`
+	Watch    *WatchEvent   `mapstructure:"watch" json:"watch,omitempty"`
+	// When filters emitted events, see Task.When
+	When string `mapstructure:"when" json:"when,omitempty"`
 
 	LogFile        string        `mapstructure:"log-file" json:"log-file,omitempty"`
 	LogCheckCycle  time.Duration `mapstructure:"log-check-cycle" json:"log-check-cycle,omitempty"`
 	LogLineBreaker string        `mapstructure:"log-line-breaker" json:"log-line-breaker,omitempty"`
 	LogMatcher     string        `mapstructure:"log-matcher" json:"log-matcher,omitempty"`
+	// LogMultilineStart marks the first line of records spanning multiple lines (e.g. stack traces)
+	LogMultilineStart string   `mapstructure:"log-multiline-start" json:"log-multiline-start,omitempty"`
+	LogFormat         string   `mapstructure:"log-format" json:"log-format,omitempty"`
+	LogConditions     []string `mapstructure:"log-conditions" json:"log-conditions,omitempty"`
+
+	// CatchUp is populated from the job owning a cron event when the job is initialized, it is not configurable on events
+	CatchUp *CatchUp `mapstructure:"-" json:"-"`
+}
+
+// CatchUp is the catch-up configuration of a cron event.
+type CatchUp struct {
+	Job      string
+	Policy   CatchUpPolicy
+	Lookback time.Duration
 }
 
 // DockerEvent represents a Docker event configuration.
@@ -57,21 +108,50 @@ type DockerEvent struct {
 	ErrorLimit       uint              `mapstructure:"error-limit-count" json:"error-limit,omitempty"`
 	ErrorLimitPolicy ErrorLimitPolicy  `mapstructure:"error-limit-policy" json:"error-limit-policy,omitempty"`
 	ErrorThrottle    time.Duration     `mapstructure:"error-throttle" json:"error-throttle,omitempty"`
+
+	// Type limits events to objects of this type (container, image, network, volume or daemon), defaults to every type
+	Type string `mapstructure:"type" json:"type,omitempty"`
+	// Since replays events emitted this long before the first connection, reconnections always resume from the last received event
+	Since time.Duration `mapstructure:"since" json:"since,omitempty"`
+}
+
+// WatchEvent represents a filesystem watch event configuration.
+// Paths can be files, directories or glob patterns (globs are applied on file names).
+type WatchEvent struct {
+	Paths     []string      `mapstructure:"paths" json:"paths,omitempty"`
+	Recursive bool          `mapstructure:"recursive" json:"recursive,omitempty"`
+	Ops       []string      `mapstructure:"ops" json:"ops,omitempty"`
+	Debounce  time.Duration `mapstructure:"debounce" json:"debounce,omitempty"`
 }
 
 // JobHooks represents the hooks configuration for a job.
+// Done and Failed are executed once per task, the rest are executed once per run (event).
 type JobHooks struct {
 	Done   []Task `mapstructure:"done" json:"done,omitempty"`
 	Failed []Task `mapstructure:"failed" json:"failed,omitempty"`
+
+	OnStart   []Task `mapstructure:"on-start" json:"on-start,omitempty"`
+	OnSuccess []Task `mapstructure:"on-success" json:"on-success,omitempty"`
+	OnFailure []Task `mapstructure:"on-failure" json:"on-failure,omitempty"`
+	Finally   []Task `mapstructure:"finally" json:"finally,omitempty"`
 }
 
 // Task represents the configuration for a task within a job.
 type Task struct {
+	// Dependency graph
+	ID    string   `mapstructure:"id" json:"id,omitempty"`
+	Needs []string `mapstructure:"needs" json:"needs,omitempty"`
+
 	// Http Requests
 	Post    string            `mapstructure:"post" json:"post,omitempty"`
 	Get     string            `mapstructure:"get" json:"get,omitempty"`
 	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty"`
 	Data    any               `mapstructure:"data" json:"data,omitempty"`
+	HTTP    *HTTPRequest      `mapstructure:"http" json:"http,omitempty"`
+	Expect  *HTTPExpect       `mapstructure:"expect" json:"expect,omitempty"`
+	TLS     *HTTPTLS          `mapstructure:"tls" json:"tls,omitempty"`
+	Proxy   string            `mapstructure:"proxy" json:"proxy,omitempty"`
+	Auth    *HTTPAuth         `mapstructure:"auth" json:"auth,omitempty"`
 
 	// Command params
 	Command          string            `mapstructure:"command" json:"command,omitempty"`
@@ -90,6 +170,10 @@ type Task struct {
 	RetryModifier string        `mapstructure:"retry-mode" json:"retry-mode,omitempty"`
 
 	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty"`
+	// StopSignal is sent to the process group of the command once it times out or is canceled,
+	// the group is killed if it does not exit within StopGrace
+	StopSignal string        `mapstructure:"stop-signal" json:"stop-signal,omitempty"`
+	StopGrace  time.Duration `mapstructure:"stop-grace" json:"stop-grace,omitempty"`
 
 	// Hooks
 	OnDone []Task `mapstructure:"on-done" json:"on-done,omitempty"`
@@ -97,6 +181,68 @@ type Task struct {
 
 	// Misc
 	Vars map[string]string `mapstructure:"vars" json:"vars,omitempty"`
+	// When is a template expression (e.g. `eq .name "web"`) evaluated against event data, `.Vars` and `.Env`,
+	// the task is skipped if it does not hold
+	When string `mapstructure:"when" json:"when,omitempty"`
+}
+
+// HTTPRequest represents the configuration of a generic http request task.
+// Headers and json body (data) are shared with get/post tasks and configured on the task itself.
+type HTTPRequest struct {
+	URL          string            `mapstructure:"url" json:"url,omitempty"`
+	Method       string            `mapstructure:"method" json:"method,omitempty"`
+	Query        map[string]string `mapstructure:"query" json:"query,omitempty"`
+	Body         string            `mapstructure:"body" json:"body,omitempty"`
+	Form         map[string]string `mapstructure:"form" json:"form,omitempty"`
+	ContentType  string            `mapstructure:"content-type" json:"content-type,omitempty"`
+	Redirect     RedirectPolicy    `mapstructure:"redirect" json:"redirect,omitempty"`
+	MaxRedirects uint              `mapstructure:"max-redirects" json:"max-redirects,omitempty"`
+}
+
+// HTTPExpect represents assertions on the response of http tasks, violating any of them fails the task.
+type HTTPExpect struct {
+	Status []string `mapstructure:"status" json:"status,omitempty"`
+	Body   string   `mapstructure:"body" json:"body,omitempty"`
+	JSON   []string `mapstructure:"json" json:"json,omitempty"`
+}
+
+// HTTPTLS represents tls settings of http tasks, files are read again once they are modified.
+type HTTPTLS struct {
+	CA                 string `mapstructure:"ca" json:"ca,omitempty"`
+	Cert               string `mapstructure:"cert" json:"cert,omitempty"`
+	Key                string `mapstructure:"key" json:"key,omitempty"`
+	MinVersion         string `mapstructure:"min-version" json:"min-version,omitempty"`
+	ServerName         string `mapstructure:"server-name" json:"server-name,omitempty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure-skip-verify" json:"insecure-skip-verify,omitempty"`
+}
+
+// HTTPAuth represents authentication of http tasks, exactly one of the methods must be set.
+type HTTPAuth struct {
+	Basic  *BasicAuth  `mapstructure:"basic" json:"basic,omitempty"`
+	Bearer *BearerAuth `mapstructure:"bearer" json:"bearer,omitempty"`
+	OAuth2 *OAuth2Auth `mapstructure:"oauth2" json:"oauth2,omitempty"`
+}
+
+// BasicAuth represents http basic authentication, password can be read from a file instead.
+type BasicAuth struct {
+	Username     string `mapstructure:"username" json:"username,omitempty"`
+	Password     string `mapstructure:"password" json:"password,omitempty"`
+	PasswordFile string `mapstructure:"password-file" json:"password-file,omitempty"`
+}
+
+// BearerAuth represents a static bearer token read from a file on every request.
+type BearerAuth struct {
+	TokenFile string `mapstructure:"token-file" json:"token-file,omitempty"`
+}
+
+// OAuth2Auth represents the OAuth2 client-credentials flow, tokens are cached until they expire or are rejected.
+type OAuth2Auth struct {
+	TokenURL         string            `mapstructure:"token-url" json:"token-url,omitempty"`
+	ClientID         string            `mapstructure:"client-id" json:"client-id,omitempty"`
+	ClientSecret     string            `mapstructure:"client-secret" json:"client-secret,omitempty"`
+	ClientSecretFile string            `mapstructure:"client-secret-file" json:"client-secret-file,omitempty"`
+	Scopes           []string          `mapstructure:"scopes" json:"scopes,omitempty"`
+	Params           map[string]string `mapstructure:"params" json:"params,omitempty"`
 }
 
 // TaskConnection represents the connection configuration for a task.
@@ -108,8 +254,106 @@ type TaskConnection struct {
 	ImageName        string   `mapstructure:"image" json:"image,omitempty"`
 	Volumes          []string `mapstructure:"volumes" json:"volumes,omitempty"`
 	Networks         []string `mapstructure:"networks" json:"networks,omitempty"`
+
+	// Tty allocates a pseudo-terminal for the command (docker), stdout and stderr are merged if set
+	Tty        bool `mapstructure:"tty" json:"tty,omitempty"`
+	Privileged bool `mapstructure:"privileged" json:"privileged,omitempty"`
+
+	// Container lifecycle (docker create)
+	PullPolicy PullPolicy        `mapstructure:"pull-policy" json:"pull-policy,omitempty"`
+	CPUs       float64           `mapstructure:"cpus" json:"cpus,omitempty"`
+	Memory     string            `mapstructure:"memory" json:"memory,omitempty"`
+	AutoRemove bool              `mapstructure:"auto-remove" json:"auto-remove,omitempty"`
+	Labels     map[string]string `mapstructure:"labels" json:"labels,omitempty"`
+	EnvFiles   []string          `mapstructure:"env-files" json:"env-files,omitempty"`
+
+	// Fan-out of the command across containers matching the label (docker attach)
+	FanOut        FanOut        `mapstructure:"fan-out" json:"fan-out,omitempty"`
+	Sequential    bool          `mapstructure:"sequential" json:"sequential,omitempty"`
+	SuccessPolicy SuccessPolicy `mapstructure:"success-policy" json:"success-policy,omitempty"`
+	Quorum        uint          `mapstructure:"quorum" json:"quorum,omitempty"`
 }
 
+// PullPolicy defines when the image of docker-create connections is pulled.
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image before every execution.
+	PullAlways PullPolicy = "always"
+	// PullMissing pulls the image only if it is not present (default).
+	PullMissing PullPolicy = "missing"
+	// PullNever never pulls the image, the execution fails if it is not present.
+	PullNever PullPolicy = "never"
+)
+
+// FanOut defines which of the containers matching the label of docker-attach connections execute the command.
+type FanOut string
+
+const (
+	// FanOutAll executes the command in every matching container.
+	FanOutAll FanOut = "all"
+	// FanOutOne executes the command in the first matching container.
+	FanOutOne FanOut = "one"
+	// FanOutRandom executes the command in a randomly chosen matching container.
+	FanOutRandom FanOut = "random"
+)
+
+// SuccessPolicy defines when a command fanned out across containers is considered successful.
+type SuccessPolicy string
+
+const (
+	// SuccessAll requires the command to succeed in every container (default).
+	SuccessAll SuccessPolicy = "all"
+	// SuccessAny requires the command to succeed in at least one container.
+	SuccessAny SuccessPolicy = "any"
+	// SuccessQuorum requires the command to succeed in `quorum` containers (defaults to the majority).
+	SuccessQuorum SuccessPolicy = "quorum"
+)
+
+// JobMode defines how the tasks of a job are executed once an event is received.
+type JobMode string
+
+const (
+	// JobModeParallel runs every task of the job in its own goroutine (default).
+	JobModeParallel JobMode = "parallel"
+	// JobModeSequential runs tasks one after another, stopping at the first failure.
+	JobModeSequential JobMode = "sequential"
+)
+
+// OverlapPolicy defines what happens to an event that arrives while previous runs of the job still occupy all of its slots.
+type OverlapPolicy string
+
+const (
+	// OverlapQueue waits for a free slot, optionally limited to max-queue pending events (default).
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapSkip drops the event.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapReplace cancels the oldest running execution and starts a fresh one.
+	OverlapReplace OverlapPolicy = "replace"
+)
+
+// CatchUpPolicy defines which cron ticks missed during downtime are emitted once the application boots.
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone ignores missed ticks (default).
+	CatchUpNone CatchUpPolicy = "none"
+	// CatchUpOnce emits a single event for the latest missed tick.
+	CatchUpOnce CatchUpPolicy = "once"
+	// CatchUpAll emits an event for every missed tick, oldest first.
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+// RedirectPolicy defines how http tasks handle redirect responses.
+type RedirectPolicy string
+
+const (
+	// RedirectFollow follows redirects up to max-redirects (default).
+	RedirectFollow RedirectPolicy = "follow"
+	// RedirectNone returns the redirect response itself.
+	RedirectNone RedirectPolicy = "none"
+)
+
 type ErrorLimitPolicy string
 
 const (
@@ -117,3 +361,15 @@ const (
 	ErrorPolGiveUp    ErrorLimitPolicy = "give-up"
 	ErrorPolReconnect ErrorLimitPolicy = "reconnect"
 )
+
+// InitMode defines whether orphaned processes left behind by commands are reaped.
+type InitMode string
+
+const (
+	// InitModeAuto reaps orphans only if the application is PID 1 (default).
+	InitModeAuto InitMode = "auto"
+	// InitModeEnabled always reaps orphans, the application becomes a child subreaper if it is not PID 1.
+	InitModeEnabled InitMode = "enabled"
+	// InitModeDisabled never reaps orphans.
+	InitModeDisabled InitMode = "disabled"
+)
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 
@@ -49,3 +50,39 @@ func TestConfig_Validate_NoJobs(t *testing.T) {
 	err := cfg.Validate()
 	assert.NoError(t, err)
 }
+
+func TestConfig_Validate_CatchUpRequiresStateDir(t *testing.T) {
+	job := &config.JobConfig{
+		Name:    "backup",
+		CatchUp: config.CatchUpAll,
+		Tasks:   []config.Task{{Command: "echo"}},
+		Events:  []config.JobEvent{{Cron: "0 3 * * *"}},
+	}
+	cfg := &config.Config{Jobs: []*config.JobConfig{job}}
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "catch-up requires a state directory")
+
+	cfg.StateDir = t.TempDir()
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfig_Validate_Shutdown(t *testing.T) {
+	cfg := &config.Config{ShutdownGrace: time.Minute, StopSignal: "SIGINT"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.StopSignal = "SIGNOPE"
+	assert.Error(t, cfg.Validate())
+
+	cfg.StopSignal = ""
+	cfg.ShutdownGrace = -time.Second
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfig_Validate_InitMode(t *testing.T) {
+	cfg := &config.Config{InitMode: config.InitModeEnabled}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.InitMode = "always"
+	assert.Error(t, cfg.Validate())
+}
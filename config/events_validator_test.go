@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"go.uber.org/zap"
@@ -85,3 +86,134 @@ func TestJobEvent_Validate_MultipleActiveSchedules(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), expectedErr)
 }
+
+func TestJobEvent_Validate_Watch(t *testing.T) {
+	event := config.JobEvent{
+		Watch: &config.WatchEvent{
+			Paths:     []string{"/data/drop/*.csv"},
+			Recursive: true,
+			Ops:       []string{"create", "Rename"},
+		},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestJobEvent_Validate_WatchInvalidOp(t *testing.T) {
+	event := config.JobEvent{
+		Watch: &config.WatchEvent{
+			Paths: []string{"/data/drop"},
+			Ops:   []string{"open"},
+		},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown watch operation")
+}
+
+func TestJobEvent_Validate_WatchWithoutPaths(t *testing.T) {
+	event := config.JobEvent{
+		Watch: &config.WatchEvent{},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "watch event must have at least one path")
+}
+
+func TestJobEvent_Validate_WatchDirectoryPattern(t *testing.T) {
+	event := config.JobEvent{
+		Watch: &config.WatchEvent{
+			Paths: []string{"/data/*/drop/*.csv"},
+		},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "directories are not expanded, patterns are only supported in the file name")
+	assert.Contains(t, err.Error(), "`/data/*/drop`")
+}
+
+func TestJobEvent_Validate_LogFileStructured(t *testing.T) {
+	event := config.JobEvent{
+		LogFile:           "/var/log/app.json",
+		LogMultilineStart: `^\{`,
+		LogFormat:         "json",
+		LogConditions:     []string{`level == "error"`, `service =~ "api.*"`},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestJobEvent_Validate_LogFileInvalidFormat(t *testing.T) {
+	event := config.JobEvent{
+		LogFile:   "/var/log/app.log",
+		LogFormat: "xml",
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown log-format")
+}
+
+func TestJobEvent_Validate_LogFileConditionsWithoutFormat(t *testing.T) {
+	event := config.JobEvent{
+		LogFile:       "/var/log/app.log",
+		LogConditions: []string{`level == "error"`},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "log-conditions require a log-format")
+}
+
+func TestJobEvent_Validate_LogFileInvalidCondition(t *testing.T) {
+	event := config.JobEvent{
+		LogFile:       "/var/log/app.log",
+		LogFormat:     "logfmt",
+		LogConditions: []string{`level`},
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid condition")
+}
+
+func TestJobEvent_Validate_InvalidWhen(t *testing.T) {
+	event := config.JobEvent{
+		OnInit: true,
+		When:   `eq .name (`,
+	}
+
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid when expression")
+}
+
+func TestJobEvent_Validate_DockerType(t *testing.T) {
+	event := config.JobEvent{
+		Docker: &config.DockerEvent{
+			Type:    "volume",
+			Actions: []string{"prune"},
+			Since:   time.Minute,
+		},
+	}
+	assert.NoError(t, event.Validate(zap.NewNop()))
+
+	event.Docker.Type = "plugin"
+	err := event.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "given type")
+}
+
+func TestJobEvent_Validate_DockerInvalidLabelMatcher(t *testing.T) {
+	event := config.JobEvent{
+		Docker: &config.DockerEvent{
+			Labels: map[string]string{"env": "prod("},
+		},
+	}
+	assert.Error(t, event.Validate(zap.NewNop()))
+}
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"go.uber.org/zap"
@@ -71,3 +72,181 @@ func TestJobConfig_Validate_HooksFailed(t *testing.T) {
 	err := jobConfig.Validate(zap.NewNop())
 	assert.Error(t, err, "Expected error due to invalid failed hook task configuration")
 }
+
+func TestJobConfig_Validate_SequentialMode(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Mode: config.JobModeSequential,
+		Tasks: []config.Task{
+			{Command: "echo"},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestJobConfig_Validate_InvalidMode(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Mode: "random",
+		Tasks: []config.Task{
+			{Command: "echo"},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "given job mode")
+}
+
+func TestJobConfig_Validate_TaskGraph(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Tasks: []config.Task{
+			{ID: "dump_users", Command: "echo users"},
+			{ID: "dump_orders", Command: "echo orders"},
+			{ID: "manifest", Command: "echo manifest", Needs: []string{"dump_users", "dump_orders"}},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestJobConfig_Validate_TaskGraphUnknownID(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Tasks: []config.Task{
+			{ID: "upload", Command: "echo", Needs: []string{"dump"}},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown task id")
+}
+
+func TestJobConfig_Validate_TaskGraphDuplicateID(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Tasks: []config.Task{
+			{ID: "dump", Command: "echo"},
+			{ID: "dump", Command: "echo"},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is used more than once")
+}
+
+func TestJobConfig_Validate_TaskGraphCycle(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Tasks: []config.Task{
+			{ID: "a", Command: "echo", Needs: []string{"c"}},
+			{ID: "b", Command: "echo", Needs: []string{"a"}},
+			{ID: "c", Command: "echo", Needs: []string{"b"}},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "form a cycle")
+}
+
+func TestJobConfig_Validate_TaskGraphSequential(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Mode: config.JobModeSequential,
+		Tasks: []config.Task{
+			{ID: "a", Command: "echo"},
+			{ID: "b", Command: "echo", Needs: []string{"a"}},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestJobConfig_Validate_OverlapPolicy(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Overlap:  config.OverlapQueue,
+		MaxQueue: 3,
+		Tasks: []config.Task{
+			{Command: "echo"},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestJobConfig_Validate_MaxQueue(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		MaxQueue: -1,
+		Tasks: []config.Task{
+			{Command: "echo"},
+		},
+	}
+	assert.NoError(t, jobConfig.Validate(zap.NewNop()))
+
+	jobConfig.MaxQueue = -2
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "max-queue")
+}
+
+func TestJobConfig_Validate_InvalidOverlapPolicy(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Overlap: "wait",
+		Tasks: []config.Task{
+			{Command: "echo"},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "given overlap policy")
+}
+
+func TestJobConfig_Validate_HooksFinally(t *testing.T) {
+	jobConfig := &config.JobConfig{
+		Hooks: config.JobHooks{
+			Finally: []config.Task{
+				{Command: "echo", Get: "http://example.com"}, // Invalid task with both command and get
+			},
+		},
+	}
+
+	err := jobConfig.Validate(zap.NewNop())
+	assert.Error(t, err, "Expected error due to invalid finally hook task configuration")
+}
+
+func TestJobConfig_Validate_CatchUp(t *testing.T) {
+	job := &config.JobConfig{
+		Name:            "backup",
+		CatchUp:         config.CatchUpOnce,
+		CatchUpLookback: 48 * time.Hour,
+		Tasks:           []config.Task{{Command: "echo"}},
+		Events:          []config.JobEvent{{Cron: "0 3 * * *"}},
+	}
+	assert.NoError(t, job.Validate(zap.NewNop()))
+
+	job.CatchUp = "sometimes"
+	err := job.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "catch-up policy")
+
+	job.CatchUp = config.CatchUpAll
+	assert.NoError(t, job.Validate(zap.NewNop()))
+	job.MaxQueue = 1
+	err = job.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "catch-up `all` requires the queue overlap policy")
+	job.MaxQueue = 0
+	job.Overlap = config.OverlapSkip
+	err = job.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "catch-up `all` requires the queue overlap policy")
+	job.Overlap = ""
+
+	job.Name = ""
+	err = job.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "catch-up requires the job to have a name")
+}
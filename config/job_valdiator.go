@@ -1,13 +1,28 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/docker/docker/api/types/events"
 	"go.uber.org/zap"
 
+	"github.com/fmotalleb/crontab-go/core/logfields"
 	"github.com/fmotalleb/crontab-go/core/utils"
+	"github.com/fmotalleb/crontab-go/core/when"
+)
+
+var acceptedEventTypes = utils.NewList(
+	"",
+	events.ContainerEventType,
+	events.ImageEventType,
+	events.NetworkEventType,
+	events.VolumeEventType,
+	events.DaemonEventType,
 )
 
 var acceptedActions = utils.NewList(
@@ -71,8 +86,12 @@ func (c *JobConfig) Validate(log *zap.Logger) error {
 		return nil
 	}
 	checkList := []func(*JobConfig, *zap.Logger) error{
+		validateMode,
+		validateOverlap,
+		validateCatchUp,
 		validateEvents,
 		validateTasks,
+		validateTaskGraph,
 		validateJobHooks,
 	}
 	for _, check := range checkList {
@@ -86,6 +105,60 @@ func (c *JobConfig) Validate(log *zap.Logger) error {
 	return nil
 }
 
+func validateMode(c *JobConfig, log *zap.Logger) error {
+	if !utils.NewList("", JobModeParallel, JobModeSequential).Contains(c.Mode) {
+		err := fmt.Errorf("given job mode: %#v is not allowed, possible modes are (parallel,sequential)", c.Mode)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func validateOverlap(c *JobConfig, log *zap.Logger) error {
+	if !utils.NewList("", OverlapQueue, OverlapSkip, OverlapReplace).Contains(c.Overlap) {
+		err := fmt.Errorf("given overlap policy: %#v is not allowed, possible policies are (queue,skip,replace)", c.Overlap)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	if c.MaxQueue < -1 {
+		err := fmt.Errorf("max-queue must be -1 (unlimited) or positive, received `%d`", c.MaxQueue)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	if c.MaxQueue != 0 && !utils.NewList("", OverlapQueue).Contains(c.Overlap) {
+		log.Warn("max-queue is only used by queue overlap policy, it will be ignored", zap.Any("overlap", c.Overlap))
+	}
+	return nil
+}
+
+func validateCatchUp(c *JobConfig, log *zap.Logger) error {
+	if !utils.NewList("", CatchUpNone, CatchUpOnce, CatchUpAll).Contains(c.CatchUp) {
+		err := fmt.Errorf("given catch-up policy: %#v is not allowed, possible policies are (none,once,all)", c.CatchUp)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	if c.CatchUpLookback < 0 {
+		err := fmt.Errorf("received a negative catch-up-lookback: `%v`", c.CatchUpLookback)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	if c.CatchesUp() && c.Name == "" {
+		err := errors.New("catch-up requires the job to have a name, last ticks are persisted by job name")
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	// replayed ticks are emitted at once, every one of them must be queued to run
+	if c.CatchUp == CatchUpAll && (!utils.NewList("", OverlapQueue).Contains(c.Overlap) || c.MaxQueue > 0) {
+		err := errors.New("catch-up `all` requires the queue overlap policy without a max-queue limit, otherwise replayed ticks are dropped")
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	if c.CatchesUp() && !slices.ContainsFunc(c.Events, func(e JobEvent) bool { return e.Cron != "" }) {
+		log.Warn("catch-up is only used by cron events, it will be ignored", zap.Any("catch-up", c.CatchUp))
+	}
+	return nil
+}
+
 func validateTasks(c *JobConfig, log *zap.Logger) error {
 	for _, t := range c.Tasks {
 		if err := t.Validate(log); err != nil {
@@ -96,6 +169,88 @@ func validateTasks(c *JobConfig, log *zap.Logger) error {
 	return nil
 }
 
+var taskIDMatcher = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateTaskGraph checks task ids and their dependencies,
+// ids must be unique and usable as template keys, needs must reference known ids and must not form a cycle.
+func validateTaskGraph(c *JobConfig, log *zap.Logger) error {
+	ids := make(map[string]int, len(c.Tasks))
+	for i, t := range c.Tasks {
+		if t.ID == "" {
+			continue
+		}
+		if !taskIDMatcher.MatchString(t.ID) {
+			err := fmt.Errorf("task id: %#v is not valid, ids may only contain letters, digits and underscore and must not start with a digit", t.ID)
+			log.Warn("Validation failed for JobConfig", zap.Error(err))
+			return err
+		}
+		if _, ok := ids[t.ID]; ok {
+			err := fmt.Errorf("task id: %#v is used more than once", t.ID)
+			log.Warn("Validation failed for JobConfig", zap.Error(err))
+			return err
+		}
+		ids[t.ID] = i
+	}
+	edges := make([][]int, len(c.Tasks))
+	for i, t := range c.Tasks {
+		if len(t.Needs) != 0 && c.Mode == JobModeSequential {
+			err := fmt.Errorf("task needs cannot be used in sequential mode, received needs: %v", t.Needs)
+			log.Warn("Validation failed for JobConfig", zap.Error(err))
+			return err
+		}
+		for _, need := range t.Needs {
+			parent, ok := ids[need]
+			if !ok {
+				err := fmt.Errorf("task needs an unknown task id: %#v", need)
+				log.Warn("Validation failed for JobConfig", zap.Error(err))
+				return err
+			}
+			edges[i] = append(edges[i], parent)
+		}
+	}
+	if cycle := findCycle(edges); cycle >= 0 {
+		err := fmt.Errorf("task dependencies form a cycle, involving task: %#v", c.Tasks[cycle].ID)
+		log.Warn("Validation failed for JobConfig", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// findCycle returns index of a node that is part of a cycle or -1 if the graph is acyclic.
+func findCycle(edges [][]int) int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(edges))
+	var visit func(int) int
+	visit = func(n int) int {
+		state[n] = visiting
+		for _, parent := range edges[n] {
+			switch state[parent] {
+			case visiting:
+				return parent
+			case unvisited:
+				if cycle := visit(parent); cycle >= 0 {
+					return cycle
+				}
+			}
+		}
+		state[n] = visited
+		return -1
+	}
+	for n := range edges {
+		if state[n] != unvisited {
+			continue
+		}
+		if cycle := visit(n); cycle >= 0 {
+			return cycle
+		}
+	}
+	return -1
+}
+
 func validateEvents(c *JobConfig, log *zap.Logger) error {
 	for _, s := range c.Events {
 		if err := s.Validate(log); err != nil {
@@ -119,6 +274,20 @@ func validateJobHooks(c *JobConfig, log *zap.Logger) error {
 			return err
 		}
 	}
+	runHooks := map[string][]Task{
+		"on-start":   c.Hooks.OnStart,
+		"on-success": c.Hooks.OnSuccess,
+		"on-failure": c.Hooks.OnFailure,
+		"finally":    c.Hooks.Finally,
+	}
+	for name, hooks := range runHooks {
+		for _, t := range hooks {
+			if err := t.Validate(log); err != nil {
+				log.Error("Validation error in run hook for JobConfig", zap.String("hook", name), zap.Error(err))
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -126,6 +295,10 @@ func validateJobHooks(c *JobConfig, log *zap.Logger) error {
 // It ensures that the event has a valid interval or cron expression, and only one of on_init, interval, or cron is set.
 // It returns an error if the validation fails, otherwise, it returns nil.
 func (s *JobEvent) Validate(log *zap.Logger) error {
+	if _, err := when.Compile(s.When); err != nil {
+		log.Warn("Validation failed for JobEvent", zap.Error(err))
+		return err
+	}
 	// Check if the interval is a negative value
 	if s.Interval < 0 {
 		err := fmt.Errorf("received a negative time in interval: `%v`", s.Interval)
@@ -139,6 +312,16 @@ func (s *JobEvent) Validate(log *zap.Logger) error {
 		if returnValue != nil {
 			return returnValue
 		}
+	} else if s.Watch != nil {
+		if err := watchValidation(s.Watch); err != nil {
+			log.Warn("Validation failed for JobEvent", zap.Error(err))
+			return err
+		}
+	} else if s.LogFile != "" {
+		if err := logFileValidation(s); err != nil {
+			log.Warn("Validation failed for JobEvent", zap.Error(err))
+			return err
+		}
 	}
 
 	// Check the active events to ensure only one of on_init, interval, docker, or cron is set
@@ -147,6 +330,7 @@ func (s *JobEvent) Validate(log *zap.Logger) error {
 		s.WebEvent != "",
 		s.Docker != nil,
 		s.LogFile != "",
+		s.Watch != nil,
 		s.OnInit,
 	)
 	activeEvents := utils.Fold(events, 0, func(c int, item bool) int {
@@ -158,13 +342,14 @@ func (s *JobEvent) Validate(log *zap.Logger) error {
 
 	if activeEvents != 1 {
 		err := fmt.Errorf(
-			"a single event must have one of (on-init: true,interval,cron,web-event,docker,log-file) field, received:(on_init: %t,cron: `%s`, interval: `%s`, web_event: `%s`, docker: %v,log-file: %v)",
+			"a single event must have one of (on-init: true,interval,cron,web-event,docker,log-file,watch) field, received:(on_init: %t,cron: `%s`, interval: `%s`, web_event: `%s`, docker: %v,log-file: %v,watch: %v)",
 			s.OnInit,
 			s.Cron,
 			s.Interval,
 			s.WebEvent,
 			s.Docker,
 			s.LogFile,
+			s.Watch,
 		)
 		log.Warn("Validation failed for JobEvent", zap.Error(err))
 		return err
@@ -175,6 +360,58 @@ func (s *JobEvent) Validate(log *zap.Logger) error {
 	return nil
 }
 
+var watchOps = utils.NewList("create", "write", "remove", "rename", "chmod")
+
+func watchValidation(w *WatchEvent) error {
+	if len(w.Paths) == 0 {
+		return errors.New("watch event must have at least one path")
+	}
+	for _, path := range w.Paths {
+		if _, err := filepath.Match(filepath.Base(path), ""); err != nil {
+			return fmt.Errorf("invalid watch path `%s`: %w", path, err)
+		}
+		if dir := filepath.Dir(path); strings.ContainsAny(dir, "*?[") {
+			return fmt.Errorf(
+				"invalid watch path `%s`: directories are not expanded, patterns are only supported in the file name (last element) but `%s` contains one, "+
+					"watch the parent directory with a file name pattern (and `recursive` for nested directories) instead",
+				path,
+				dir,
+			)
+		}
+	}
+	for _, op := range w.Ops {
+		if !watchOps.Contains(strings.ToLower(op)) {
+			return fmt.Errorf("unknown watch operation `%s`, expected one of %v", op, watchOps.Slice())
+		}
+	}
+	if w.Debounce < 0 {
+		return fmt.Errorf("received a negative watch debounce: `%v`", w.Debounce)
+	}
+	return nil
+}
+
+func logFileValidation(s *JobEvent) error {
+	if _, err := regexp.Compile(s.LogMatcher); err != nil {
+		return fmt.Errorf("invalid log-matcher `%s`: %w", s.LogMatcher, err)
+	}
+	if _, err := regexp.Compile(s.LogMultilineStart); err != nil {
+		return fmt.Errorf("invalid log-multiline-start `%s`: %w", s.LogMultilineStart, err)
+	}
+	if s.LogFormat == "" {
+		if len(s.LogConditions) != 0 {
+			return errors.New("log-conditions require a log-format")
+		}
+		return nil
+	}
+	if !slices.Contains(logfields.Formats, s.LogFormat) {
+		return fmt.Errorf("unknown log-format `%s`, expected one of %v", s.LogFormat, logfields.Formats)
+	}
+	if _, err := logfields.Compile(s.LogConditions); err != nil {
+		return err
+	}
+	return nil
+}
+
 func dockerValidation(s *JobEvent, log *zap.Logger) error {
 	// Check if regex matchers are valid
 	checkList := utils.NewList[string]()
@@ -185,17 +422,27 @@ func dockerValidation(s *JobEvent, log *zap.Logger) error {
 	for _, v := range s.Docker.Labels {
 		checkList.Add(v)
 	}
-	err := utils.Fold(checkList, nil, func(initial error, _ string) error {
+	err := utils.Fold(checkList, nil, func(initial error, pattern string) error {
 		if initial != nil {
 			return initial
 		}
-		_, err := regexp.Compile(s.Docker.Name)
+		_, err := regexp.Compile(pattern)
 		return err
 	})
 	if err != nil {
 		log.Warn("Validation failed for one of docker regex pattern (container name, image name, labels value)", zap.Error(err))
 		return err
 	}
+	if !acceptedEventTypes.Contains(events.Type(s.Docker.Type)) {
+		err := fmt.Errorf("given type: %#v is not allowed, possible types are (container,image,network,volume,daemon)", s.Docker.Type)
+		log.Warn("Validation failed for docker event type", zap.Error(err))
+		return err
+	}
+	if s.Docker.Since < 0 {
+		err := fmt.Errorf("received a negative since value: `%v`", s.Docker.Since)
+		log.Warn("Validation failed for docker, since value error", zap.Error(err))
+		return err
+	}
 	for _, i := range s.Docker.Actions {
 		if !acceptedActions.Contains(events.Action(i)) {
 			err := fmt.Errorf("given action: %#v is not allowed", i)
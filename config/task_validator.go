@@ -2,15 +2,24 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/docker/go-units"
 	"go.uber.org/zap"
 
+	"github.com/fmotalleb/crontab-go/core/expect"
 	credential "github.com/fmotalleb/crontab-go/core/os_credential"
+	"github.com/fmotalleb/crontab-go/core/process"
+	"github.com/fmotalleb/crontab-go/core/utils"
+	"github.com/fmotalleb/crontab-go/core/when"
 )
 
 // Validate checks the validity of a Task.
-// It ensures that the task has exactly one of the Get, Post, or Command fields, and validates other fields based on the specified action.
+// It ensures that the task has exactly one of the Get, Post, HTTP or Command fields, and validates other fields based on the specified action.
 // If any validation fails, it returns an error with the specific validation error.
 // Otherwise, it returns nil.
 func (t *Task) Validate(log *zap.Logger) error {
@@ -22,9 +31,16 @@ func (t *Task) Validate(log *zap.Logger) error {
 		validateCredential,
 		validateFields,
 		validateGetRequest,
+		validateHTTPRequest,
+		validateExpect,
+		validateTransport,
+		validateAuth,
 		validateTimeout,
+		validateStop,
+		validateConnections,
 		validatePostData,
 		validateRetry,
+		validateWhen,
 	}
 	for _, check := range checkList {
 		if err := check(t, log); err != nil {
@@ -45,6 +61,14 @@ func (t *Task) Validate(log *zap.Logger) error {
 	return nil
 }
 
+func validateWhen(t *Task, log *zap.Logger) error {
+	if _, err := when.Compile(t.When); err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func validateRetry(t *Task, log *zap.Logger) error {
 	if t.RetryDelay < 0 {
 		err := fmt.Errorf(
@@ -82,6 +106,58 @@ func validateTimeout(t *Task, log *zap.Logger) error {
 	return nil
 }
 
+func validateStop(t *Task, log *zap.Logger) error {
+	var err error
+	switch {
+	case t.Command == "" && (t.StopSignal != "" || t.StopGrace != 0):
+		err = errors.New("stop-signal and stop-grace are only supported by command tasks")
+	case t.StopGrace < 0:
+		err = fmt.Errorf("stop grace for tasks cannot be negative received `%s`", t.StopGrace)
+	case t.StopSignal != "":
+		if _, parseErr := process.ParseSignal(t.StopSignal); parseErr != nil {
+			err = fmt.Errorf("invalid stop-signal: %w", parseErr)
+		}
+	}
+	if err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func validateConnections(t *Task, log *zap.Logger) error {
+	for _, conn := range t.Connections {
+		if err := validateConnection(conn); err != nil {
+			log.Warn("Validation failed for Task", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+func validateConnection(conn TaskConnection) error {
+	switch {
+	case !utils.NewList("", PullAlways, PullMissing, PullNever).Contains(conn.PullPolicy):
+		return fmt.Errorf("given pull-policy: %#v is not allowed, possible policies are (always,missing,never)", conn.PullPolicy)
+	case conn.CPUs < 0:
+		return fmt.Errorf("cpus of connections cannot be negative received `%v`", conn.CPUs)
+	case !utils.NewList("", FanOutAll, FanOutOne, FanOutRandom).Contains(conn.FanOut):
+		return fmt.Errorf("given fan-out: %#v is not allowed, possible values are (all,one,random)", conn.FanOut)
+	case conn.FanOut != "" && conn.ContainerLabel == "":
+		return fmt.Errorf("fan-out requires container label, received container `%s`", conn.ContainerName)
+	case !utils.NewList("", SuccessAll, SuccessAny, SuccessQuorum).Contains(conn.SuccessPolicy):
+		return fmt.Errorf("given success-policy: %#v is not allowed, possible policies are (all,any,quorum)", conn.SuccessPolicy)
+	case conn.Quorum != 0 && conn.SuccessPolicy != SuccessQuorum:
+		return fmt.Errorf("quorum is only used by `quorum` success-policy, received `%s`", conn.SuccessPolicy)
+	}
+	if conn.Memory != "" {
+		if _, err := units.RAMInBytes(conn.Memory); err != nil {
+			return fmt.Errorf("invalid memory limit: %w", err)
+		}
+	}
+	return nil
+}
+
 func validateGetRequest(t *Task, log *zap.Logger) error {
 	if t.Get != "" && t.Data != nil {
 		err := fmt.Errorf("GET request cannot have data field, violating GET URI: `%s`", t.Get)
@@ -91,6 +167,122 @@ func validateGetRequest(t *Task, log *zap.Logger) error {
 	return nil
 }
 
+func validateHTTPRequest(t *Task, log *zap.Logger) error {
+	if t.HTTP == nil {
+		return nil
+	}
+	var err error
+	bodies := 0
+	for _, set := range []bool{t.HTTP.Body != "", t.HTTP.Form != nil, t.Data != nil} {
+		if set {
+			bodies++
+		}
+	}
+	switch {
+	case t.HTTP.URL == "":
+		err = errors.New("http request must have an url")
+	case t.HTTP.Method != "" && !httpMethods.Contains(strings.ToUpper(t.HTTP.Method)):
+		err = fmt.Errorf("unsupported http method `%s`, expected one of %v", t.HTTP.Method, httpMethods.Slice())
+	case bodies > 1:
+		err = fmt.Errorf("http request can have only one of (body, form, data) fields, violating URI: `%s`", t.HTTP.URL)
+	case t.HTTP.Redirect != "" && !utils.NewList(RedirectFollow, RedirectNone).Contains(t.HTTP.Redirect):
+		err = fmt.Errorf("unknown redirect policy `%s`, expected one of (follow, none)", t.HTTP.Redirect)
+	}
+	if err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func validateExpect(t *Task, log *zap.Logger) error {
+	if t.Expect == nil {
+		return nil
+	}
+	var err error
+	if t.Command != "" {
+		err = fmt.Errorf("command cannot have expect field, violating command: `%s`", t.Command)
+	} else {
+		_, err = expect.New(t.Expect.Status, t.Expect.Body, t.Expect.JSON)
+	}
+	if err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func validateTransport(t *Task, log *zap.Logger) error {
+	var err error
+	switch {
+	case t.Command != "" && (t.TLS != nil || t.Proxy != ""):
+		err = fmt.Errorf("command cannot have tls or proxy field, violating command: `%s`", t.Command)
+	case t.Proxy != "":
+		if _, perr := url.Parse(t.Proxy); perr != nil {
+			err = fmt.Errorf("invalid proxy url: %w", perr)
+		}
+	}
+	if err == nil && t.TLS != nil {
+		switch {
+		case (t.TLS.Cert == "") != (t.TLS.Key == ""):
+			err = errors.New("tls cert and key must be set together")
+		case t.TLS.MinVersion != "" && !tlsVersions.Contains(t.TLS.MinVersion):
+			err = fmt.Errorf("unknown tls min-version `%s`, expected one of %v", t.TLS.MinVersion, tlsVersions.Slice())
+		}
+	}
+	if err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func validateAuth(t *Task, log *zap.Logger) error {
+	if t.Auth == nil {
+		return nil
+	}
+	var err error
+	methods := 0
+	for _, set := range []bool{t.Auth.Basic != nil, t.Auth.Bearer != nil, t.Auth.OAuth2 != nil} {
+		if set {
+			methods++
+		}
+	}
+	switch {
+	case t.Command != "":
+		err = fmt.Errorf("command cannot have auth field, violating command: `%s`", t.Command)
+	case methods != 1:
+		err = errors.New("auth must have exactly one of (basic, bearer, oauth2) fields")
+	case t.Auth.Basic != nil && t.Auth.Basic.Username == "":
+		err = errors.New("basic auth must have a username")
+	case t.Auth.Basic != nil && t.Auth.Basic.Password != "" && t.Auth.Basic.PasswordFile != "":
+		err = errors.New("basic auth can have only one of (password, password-file) fields")
+	case t.Auth.Bearer != nil && t.Auth.Bearer.TokenFile == "":
+		err = errors.New("bearer auth must have a token-file")
+	case t.Auth.OAuth2 != nil && (t.Auth.OAuth2.TokenURL == "" || t.Auth.OAuth2.ClientID == ""):
+		err = errors.New("oauth2 auth must have token-url and client-id")
+	case t.Auth.OAuth2 != nil && t.Auth.OAuth2.ClientSecret != "" && t.Auth.OAuth2.ClientSecretFile != "":
+		err = errors.New("oauth2 auth can have only one of (client-secret, client-secret-file) fields")
+	}
+	if err != nil {
+		log.Warn("Validation failed for Task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+var tlsVersions = utils.NewList("1.0", "1.1", "1.2", "1.3")
+
+var httpMethods = utils.NewList(
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+)
+
 func validateFields(t *Task, log *zap.Logger) error {
 	if t.Command != "" && (t.Data != nil || t.Headers != nil) {
 		err := fmt.Errorf("command cannot have data or headers field, violating command: `%s`", t.Command)
@@ -112,6 +304,7 @@ func validateActionsList(t *Task, log *zap.Logger) error {
 		t.Get != "",
 		t.Command != "",
 		t.Post != "",
+		t.HTTP != nil,
 	}
 	activeActions := 0
 	for _, t := range actions {
@@ -121,10 +314,11 @@ func validateActionsList(t *Task, log *zap.Logger) error {
 	}
 	if activeActions != 1 {
 		err := fmt.Errorf(
-			"a single task should have one of (Get, Post, Command) fields, received:(Command: `%s`, Get: `%s`, Post: `%s`)",
+			"a single task should have one of (Get, Post, HTTP, Command) fields, received:(Command: `%s`, Get: `%s`, Post: `%s`, HTTP: %+v)",
 			t.Command,
 			t.Get,
 			t.Post,
+			t.HTTP,
 		)
 		log.Warn("Validation failed for Task", zap.Error(err))
 		return err
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"go.uber.org/zap"
@@ -160,3 +161,249 @@ func TestTaskValidate_ValidCommandWithErrorHook(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "a single task should have one of")
 }
+
+func TestTaskValidate_ValidHTTPRequest(t *testing.T) {
+	task := &config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:      "http://test/cache",
+			Method:   "delete",
+			Redirect: config.RedirectNone,
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestTaskValidate_HTTPRequestInvalidMethod(t *testing.T) {
+	task := &config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:    "http://test",
+			Method: "fetch",
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported http method")
+}
+
+func TestTaskValidate_HTTPRequestMultipleBodies(t *testing.T) {
+	task := &config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:  "http://test",
+			Body: "raw",
+			Form: map[string]string{"key": "value"},
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only one of (body, form, data)")
+}
+
+func TestTaskValidate_HTTPRequestWithoutURL(t *testing.T) {
+	task := &config.Task{
+		HTTP: &config.HTTPRequest{Method: "put"},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "http request must have an url")
+}
+
+func TestTaskValidate_Expect(t *testing.T) {
+	task := &config.Task{
+		Get: "http://test",
+		Expect: &config.HTTPExpect{
+			Status: []string{"200-299"},
+			Body:   "ok",
+			JSON:   []string{`$.status == "ok"`},
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestTaskValidate_InvalidExpect(t *testing.T) {
+	task := &config.Task{
+		Get: "http://test",
+		Expect: &config.HTTPExpect{
+			JSON: []string{`status == "ok"`},
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid json expectation")
+}
+
+func TestTaskValidate_CommandWithExpect(t *testing.T) {
+	task := &config.Task{
+		Command: "test",
+		Expect:  &config.HTTPExpect{Body: "ok"},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "command cannot have expect field")
+}
+
+func TestTaskValidate_TLS(t *testing.T) {
+	task := &config.Task{
+		Get:   "https://test",
+		Proxy: "http://proxy:3128",
+		TLS: &config.HTTPTLS{
+			CA:         "/etc/ssl/ca.pem",
+			Cert:       "/etc/ssl/client.pem",
+			Key:        "/etc/ssl/client.key",
+			MinVersion: "1.2",
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestTaskValidate_TLSCertWithoutKey(t *testing.T) {
+	task := &config.Task{
+		Get: "https://test",
+		TLS: &config.HTTPTLS{Cert: "/etc/ssl/client.pem"},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tls cert and key must be set together")
+}
+
+func TestTaskValidate_TLSInvalidMinVersion(t *testing.T) {
+	task := &config.Task{
+		Get: "https://test",
+		TLS: &config.HTTPTLS{MinVersion: "2.0"},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown tls min-version")
+}
+
+func TestTaskValidate_Auth(t *testing.T) {
+	task := &config.Task{
+		Get: "https://test",
+		Auth: &config.HTTPAuth{
+			OAuth2: &config.OAuth2Auth{
+				TokenURL:         "https://auth/token",
+				ClientID:         "crontab",
+				ClientSecretFile: "/run/secrets/client",
+			},
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.NoError(t, err)
+}
+
+func TestTaskValidate_AuthMultipleMethods(t *testing.T) {
+	task := &config.Task{
+		Get: "https://test",
+		Auth: &config.HTTPAuth{
+			Basic:  &config.BasicAuth{Username: "admin"},
+			Bearer: &config.BearerAuth{TokenFile: "/run/secrets/token"},
+		},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "auth must have exactly one of")
+}
+
+func TestTaskValidate_AuthOAuth2WithoutTokenURL(t *testing.T) {
+	task := &config.Task{
+		Get:  "https://test",
+		Auth: &config.HTTPAuth{OAuth2: &config.OAuth2Auth{ClientID: "crontab"}},
+	}
+
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "oauth2 auth must have token-url and client-id")
+}
+
+func TestTaskValidate_When(t *testing.T) {
+	task := &config.Task{
+		Command: "echo",
+		When:    `eq .name "web"`,
+	}
+	assert.NoError(t, task.Validate(zap.NewNop()))
+
+	task.When = `eq .name (`
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid when expression")
+}
+
+func TestTaskValidate_Stop(t *testing.T) {
+	task := &config.Task{
+		Command:    "command",
+		StopSignal: "SIGINT",
+		StopGrace:  time.Minute,
+	}
+	assert.NoError(t, task.Validate(zap.NewNop()))
+
+	task.StopSignal = "SIGNOPE"
+	assert.Error(t, task.Validate(zap.NewNop()))
+
+	task.StopSignal = ""
+	task.StopGrace = -1
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stop grace for tasks cannot be negative")
+
+	task = &config.Task{Get: "https://localhost", StopSignal: "SIGINT"}
+	assert.Error(t, task.Validate(zap.NewNop()))
+}
+
+func TestTaskValidate_Connections(t *testing.T) {
+	task := &config.Task{
+		Command: "command",
+		Connections: []config.TaskConnection{
+			{ImageName: "alpine", PullPolicy: config.PullAlways, CPUs: 0.5, Memory: "512m"},
+		},
+	}
+	assert.NoError(t, task.Validate(zap.NewNop()))
+
+	task.Connections[0].PullPolicy = "sometimes"
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "pull-policy")
+
+	task.Connections[0].PullPolicy = config.PullNever
+	task.Connections[0].Memory = "lots"
+	assert.Error(t, task.Validate(zap.NewNop()))
+
+	task.Connections[0].Memory = ""
+	task.Connections[0].CPUs = -1
+	assert.Error(t, task.Validate(zap.NewNop()))
+}
+
+func TestTaskValidate_FanOut(t *testing.T) {
+	task := &config.Task{
+		Command: "command",
+		Connections: []config.TaskConnection{
+			{ContainerLabel: "app=web", FanOut: config.FanOutAll, SuccessPolicy: config.SuccessQuorum, Quorum: 2},
+		},
+	}
+	assert.NoError(t, task.Validate(zap.NewNop()))
+
+	task.Connections[0].FanOut = "some"
+	assert.Error(t, task.Validate(zap.NewNop()))
+
+	task.Connections[0].FanOut = config.FanOutRandom
+	task.Connections[0].SuccessPolicy = config.SuccessAny
+	err := task.Validate(zap.NewNop())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "quorum")
+
+	task.Connections[0] = config.TaskConnection{ContainerName: "web", FanOut: config.FanOutAll}
+	assert.Error(t, task.Validate(zap.NewNop()))
+}
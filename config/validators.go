@@ -4,6 +4,9 @@ import (
 	"fmt"
 
 	"github.com/fmotalleb/go-tools/log"
+
+	"github.com/fmotalleb/crontab-go/core/process"
+	"github.com/fmotalleb/crontab-go/core/utils"
 )
 
 // Validate checks the validity of the Config struct.
@@ -16,6 +19,15 @@ func (cfg *Config) Validate() error {
 	if err := validateWebserverConfig(cfg); err != nil {
 		return err
 	}
+	if err := validateStateConfig(cfg); err != nil {
+		return err
+	}
+	if err := validateShutdownConfig(cfg); err != nil {
+		return err
+	}
+	if !utils.NewList("", InitModeAuto, InitModeEnabled, InitModeDisabled).Contains(cfg.InitMode) {
+		return fmt.Errorf("given init mode: %#v is not allowed, possible modes are (auto,enabled,disabled)", cfg.InitMode)
+	}
 
 	// Validate each job in the config
 	for _, job := range cfg.Jobs {
@@ -28,6 +40,31 @@ func (cfg *Config) Validate() error {
 	return nil
 }
 
+func validateStateConfig(cfg *Config) error {
+	if cfg.StateDir != "" {
+		return nil
+	}
+	for _, job := range cfg.Jobs {
+		if !job.Disabled && job.CatchesUp() {
+			return fmt.Errorf("job %s: catch-up requires a state directory (state_dir) to persist last ticks", job.Name)
+		}
+	}
+	return nil
+}
+
+func validateShutdownConfig(cfg *Config) error {
+	if cfg.ShutdownGrace < 0 {
+		return fmt.Errorf("shutdown grace cannot be negative, received: %s", cfg.ShutdownGrace)
+	}
+	if cfg.StopSignal == "" {
+		return nil
+	}
+	if _, err := process.ParseSignal(cfg.StopSignal); err != nil {
+		return fmt.Errorf("invalid stop signal: %w", err)
+	}
+	return nil
+}
+
 func validateWebserverConfig(cfg *Config) error {
 	log := log.NewBuilder().FromEnv().MustBuild()
 	if cfg.WebServerAddress == "" {
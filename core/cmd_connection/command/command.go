@@ -60,14 +60,6 @@ func (ctx Ctx) getEnv() map[string]string {
 	return env
 }
 
-func (ctx Ctx) getVars() map[string]string {
-	env, ok := ctx.Value(ctxutils.Vars).(map[string]string)
-	if !ok {
-		return map[string]string{}
-	}
-	return env
-}
-
 func (ctx Ctx) envReshape() []string {
 	env := ctx.getEnv()
 	result := make([]string, 0, len(env))
@@ -112,15 +104,36 @@ func (ctx Ctx) BuildExecuteParams(command string) (string, []string, []string) {
 }
 
 func (ctx Ctx) applyEventTemplate(src string) (string, error) {
-	event, ok := ctx.Value(ctxutils.EventData).(abstraction.Event)
-	if !ok {
+	if _, ok := ctx.Value(ctxutils.EventData).(abstraction.Event); !ok {
 		ctx.logger.Warn("Event not found in context")
 		return src, nil
 	}
-	data := maps.Clone(event.GetData())
-	vars := ctx.getVars()
+	return applyTemplate(ctx.logger, src, TemplateData(ctx))
+}
+
+// TemplateData collects the data available to templates of tasks:
+// data of the event that triggered the job, `Vars` and `Result` of the task that triggered the hook (if any).
+func TemplateData(ctx context.Context) map[string]any {
+	data := make(map[string]any)
+	if event, ok := ctx.Value(ctxutils.EventData).(abstraction.Event); ok {
+		maps.Copy(data, event.GetData())
+	}
+	vars, ok := ctx.Value(ctxutils.Vars).(map[string]string)
+	if !ok {
+		vars = map[string]string{}
+	}
 	data["Vars"] = vars
-	return applyTemplate(ctx.logger, src, data)
+	if res := ctx.Value(ctxutils.Result); res != nil {
+		data["Result"] = res
+	}
+	return data
+}
+
+// Render applies the template on src using TemplateData of the context,
+// if the template cannot be applied src is returned as is.
+func Render(ctx context.Context, log *zap.Logger, src string) string {
+	res, _ := applyTemplate(log, src, TemplateData(ctx))
+	return res
 }
 
 func (ctx Ctx) tryTemplate(src string) string {
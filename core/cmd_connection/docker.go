@@ -0,0 +1,40 @@
+package connection
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/fmotalleb/crontab-go/core/common"
+)
+
+// ExitError is returned by commands that exited with a non-zero exit code inside a container.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode implements the exit code carrier used by common.Result.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// copyOutput reads the output stream of a container, stdout and stderr are demultiplexed unless a tty is attached
+// (which merges them into stdout), streams are also recorded separately in the result of the task.
+// It returns the combined output.
+func copyOutput(reader io.Reader, tty bool, result *common.Result) ([]byte, error) {
+	res := common.NewOutputBuffer()
+	stdout, stderr := common.NewOutputBuffer(), common.NewOutputBuffer()
+	var err error
+	if tty {
+		_, err = io.Copy(io.MultiWriter(stdout, res), reader)
+	} else {
+		_, err = stdcopy.StdCopy(io.MultiWriter(stdout, res), io.MultiWriter(stderr, res), reader)
+	}
+	result.AppendStreams(stdout.Bytes(), stderr.Bytes())
+	return res.Bytes(), err
+}
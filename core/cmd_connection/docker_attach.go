@@ -1,11 +1,11 @@
 package connection
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	"math/rand/v2"
+	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -15,6 +15,7 @@ import (
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
 )
 
 func init() {
@@ -27,6 +28,7 @@ type DockerAttachConnection struct {
 	cli     *client.Client
 	execCFG *container.ExecOptions
 	ctx     context.Context
+	result  *common.Result
 }
 
 // NewDockerAttachConnection creates a new DockerAttachConnection instance.
@@ -60,6 +62,7 @@ func NewDockerAttachConnection(log *zap.Logger, conn *config.TaskConnection) (ab
 func (d *DockerAttachConnection) Prepare(ctx context.Context, task *config.Task) error {
 	cmdCtx := command.NewCtx(ctx, task.Env, d.log)
 	d.ctx = ctx
+	d.result = common.ResultOf(ctx)
 	// Specify the container ID or name
 	if d.conn.DockerConnection == "" {
 		d.log.Debug("No explicit docker connection specified, using default: `unix:///var/run/docker.sock`")
@@ -74,8 +77,8 @@ func (d *DockerAttachConnection) Prepare(ctx context.Context, task *config.Task)
 	d.execCFG = &container.ExecOptions{
 		AttachStdout: true,
 		AttachStderr: true,
-		Tty:          true,
-		Privileged:   true,
+		Tty:          d.conn.Tty,
+		Privileged:   d.conn.Privileged,
 		Env:          environments,
 		WorkingDir:   task.WorkingDirectory,
 		User:         task.UserName,
@@ -102,38 +105,68 @@ func (d *DockerAttachConnection) Connect() error {
 }
 
 // Execute runs the command in the Docker container and captures the output.
-// It creates an exec instance, attaches to it, and reads the command output.
+// The container is either given by its name or matched by its label, in which case the fan-out of the connection
+// decides whether the command runs in one or every matching container.
 // Returns:
 // - A byte slice containing the command output.
-// - An error if the execution fails, otherwise nil.
+// - An error if the execution fails or the command exits with a non-zero exit code (an *ExitError), otherwise nil.
 func (d *DockerAttachConnection) Execute() ([]byte, error) {
-	cid := d.conn.ContainerName
-	if cid == "" {
-		label := d.conn.ContainerLabel
-		if label == "" {
-			return nil, errors.New("neither container name nor label provided")
-		}
+	if d.conn.ContainerName != "" {
+		return d.execIn(d.conn.ContainerName, d.result)
+	}
+	targets, err := d.targets()
+	if err != nil {
+		return nil, err
+	}
+	if d.conn.FanOut != config.FanOutAll {
+		return d.execIn(targets[0].id, d.result)
+	}
+	return d.fanOut(targets)
+}
 
-		args := filters.NewArgs()
-		args.Add("label", label)
+// targets lists containers matching the label of the connection according to its fan-out.
+func (d *DockerAttachConnection) targets() ([]target, error) {
+	label := d.conn.ContainerLabel
+	if label == "" {
+		return nil, errors.New("neither container name nor label provided")
+	}
 
-		containers, err := d.cli.ContainerList(d.ctx, container.ListOptions{
-			Filters: args,
-		})
-		if err != nil {
-			return nil, err
-		}
+	args := filters.NewArgs()
+	args.Add("label", label)
 
-		if len(containers) == 0 {
-			return nil, fmt.Errorf("no container found with label %q", label)
-		}
+	containers, err := d.cli.ContainerList(d.ctx, container.ListOptions{
+		Filters: args,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if len(containers) != 1 {
-			return nil, fmt.Errorf("more than one container found with label %q", label)
-		}
-		cid = containers[0].ID
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("no container found with label %q", label)
 	}
 
+	targets := make([]target, 0, len(containers))
+	for _, c := range containers {
+		targets = append(targets, targetOf(c))
+	}
+	switch d.conn.FanOut {
+	case config.FanOutAll:
+		return targets, nil
+	case config.FanOutOne:
+		return targets[:1], nil
+	case config.FanOutRandom:
+		picked := rand.IntN(len(targets))
+		return targets[picked : picked+1], nil
+	}
+	if len(targets) != 1 {
+		return nil, fmt.Errorf("more than one container found with label %q, use fan-out to select containers", label)
+	}
+	return targets, nil
+}
+
+// execIn creates an exec instance in the container, attaches to it, reads the command output
+// (demultiplexing stdout and stderr unless a tty is attached) and inspects the exec instance once the output ends.
+func (d *DockerAttachConnection) execIn(cid string, result *common.Result) ([]byte, error) {
 	// Create the exec instance
 	exec, err := d.cli.ContainerExecCreate(d.ctx, cid, *d.execCFG)
 	if err != nil {
@@ -145,7 +178,7 @@ func (d *DockerAttachConnection) Execute() ([]byte, error) {
 		d.ctx,
 		exec.ID,
 		container.ExecStartOptions{
-			Tty: true,
+			Tty: d.conn.Tty,
 		},
 	)
 	if err != nil {
@@ -155,15 +188,24 @@ func (d *DockerAttachConnection) Execute() ([]byte, error) {
 		resp.Close()
 	}()
 
-	writer := bytes.NewBuffer([]byte{})
-	// Print the command output
-	wrote, err := io.Copy(writer, resp.Reader)
-	d.log.Debug("output of stdout is fetched", zap.Int64("bytes", wrote))
+	log := d.log.With(zap.String("container", cid))
+	output, err := copyOutput(resp.Reader, d.conn.Tty, result)
+	log.Debug("output of the command is fetched", zap.Int("bytes", len(output)))
 	if err != nil {
-		d.log.Debug("copy of std is failed", zap.Int64("until-err", wrote), zap.Error(err))
-		return writer.Bytes(), err
+		log.Debug("copy of std is failed", zap.Int("until-err", len(output)), zap.Error(err))
+		return output, err
+	}
+
+	inspect, err := d.cli.ContainerExecInspect(d.ctx, exec.ID)
+	if err != nil {
+		return output, fmt.Errorf("cannot inspect the exec instance: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		err := &ExitError{Code: inspect.ExitCode}
+		log.Warn("command execution failed", zap.String("output", strings.TrimSpace(string(output))), zap.Error(err))
+		return output, err
 	}
-	return writer.Bytes(), nil
+	return output, nil
 }
 
 // Disconnect closes the connection to the Docker daemon.
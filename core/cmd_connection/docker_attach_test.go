@@ -0,0 +1,146 @@
+package connection_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	connection "github.com/fmotalleb/crontab-go/core/cmd_connection"
+	"github.com/fmotalleb/crontab-go/core/common"
+)
+
+func attach(t *testing.T, conn *config.TaskConnection) (*common.Result, []byte, error) {
+	t.Helper()
+	con := connection.Get(conn, zap.NewNop())
+	ctx, res := common.WithResult(context.Background())
+	assert.NoError(t, con.Prepare(ctx, &config.Task{Command: "backup"}))
+	assert.NoError(t, con.Connect())
+	defer func() {
+		assert.NoError(t, con.Disconnect())
+	}()
+	out, err := con.Execute()
+	return res, out, err
+}
+
+func TestDockerAttach_ExitCode(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.stdout = "dumping\n"
+	fake.stderr = "disk full\n"
+	fake.exitCode = 3
+
+	res, out, err := attach(t, &config.TaskConnection{DockerConnection: fake.Host(), ContainerName: "db"})
+	var exitErr *connection.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Equal(t, "dumping\ndisk full\n", string(out))
+	assert.Equal(t, "dumping\n", res.Stdout)
+	assert.Equal(t, "disk full\n", res.Stderr)
+
+	res.SetError(err)
+	assert.Equal(t, 3, res.ExitCode)
+
+	execs := fake.execsOf("db")
+	assert.Equal(t, 1, len(execs))
+	assert.False(t, execs[0].Tty)
+	assert.False(t, execs[0].Privileged)
+}
+
+func TestDockerAttach_Tty(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.stdout = "ok\n"
+	fake.containers = []string{"db-1"}
+
+	res, out, err := attach(t, &config.TaskConnection{
+		DockerConnection: fake.Host(),
+		ContainerLabel:   "role=db",
+		Tty:              true,
+		Privileged:       true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", string(out))
+	assert.Equal(t, "ok\n", res.Stdout)
+
+	execs := fake.execsOf("db-1")
+	assert.Equal(t, 1, len(execs))
+	assert.True(t, execs[0].Tty)
+	assert.True(t, execs[0].Privileged)
+}
+
+func TestDockerAttach_MultipleContainers(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.containers = []string{"web-1", "web-2"}
+
+	_, _, err := attach(t, &config.TaskConnection{DockerConnection: fake.Host(), ContainerLabel: "app=web"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "more than one container found")
+
+	_, _, err = attach(t, &config.TaskConnection{
+		DockerConnection: fake.Host(),
+		ContainerLabel:   "app=web",
+		FanOut:           config.FanOutOne,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fake.execsOf("web-1")))
+	assert.Equal(t, 0, len(fake.execsOf("web-2")))
+}
+
+func TestDockerAttach_FanOut(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.containers = []string{"web-1", "web-2", "web-3"}
+	fake.stdout = "cleared\n"
+
+	res, out, err := attach(t, &config.TaskConnection{
+		DockerConnection: fake.Host(),
+		ContainerLabel:   "app=web",
+		FanOut:           config.FanOutAll,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cleared\ncleared\ncleared\n", string(out))
+	assert.Equal(t, "cleared\ncleared\ncleared\n", res.Stdout)
+	for _, c := range fake.containers {
+		assert.Equal(t, 1, len(fake.execsOf(c)), c)
+	}
+}
+
+func TestDockerAttach_FanOutSuccessPolicy(t *testing.T) {
+	tests := []struct {
+		policy config.SuccessPolicy
+		quorum uint
+		failed int
+		fails  bool
+	}{
+		{policy: "", failed: 0},
+		{policy: config.SuccessAll, failed: 1, fails: true},
+		{policy: config.SuccessAny, failed: 2},
+		{policy: config.SuccessAny, failed: 3, fails: true},
+		{policy: config.SuccessQuorum, failed: 1},
+		{policy: config.SuccessQuorum, failed: 2, fails: true},
+		{policy: config.SuccessQuorum, quorum: 1, failed: 2},
+	}
+	for _, tt := range tests {
+		fake := newFakeDocker(t)
+		fake.containers = []string{"web-1", "web-2", "web-3"}
+		fake.exitCodes = map[string]int{}
+		for _, c := range fake.containers[:tt.failed] {
+			fake.exitCodes[c] = 4
+		}
+
+		res, _, err := attach(t, &config.TaskConnection{
+			DockerConnection: fake.Host(),
+			ContainerLabel:   "app=web",
+			FanOut:           config.FanOutAll,
+			Sequential:       true,
+			SuccessPolicy:    tt.policy,
+			Quorum:           tt.quorum,
+		})
+		assert.Equal(t, tt.fails, err != nil, "policy %q with %d failed: %v", tt.policy, tt.failed, err)
+		if tt.fails {
+			res.SetError(err)
+			assert.Equal(t, 4, res.ExitCode)
+		}
+	}
+}
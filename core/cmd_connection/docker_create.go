@@ -1,18 +1,26 @@
 package connection
 
 import (
-	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"maps"
+	"strings"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
 	"github.com/fmotalleb/crontab-go/core/utils"
 	"github.com/fmotalleb/crontab-go/helpers"
 )
@@ -30,6 +38,7 @@ type DockerCreateConnection struct {
 	hostConfig      *container.HostConfig
 	networkConfig   *network.NetworkingConfig
 	ctx             context.Context
+	result          *common.Result
 }
 
 // NewDockerCreateConnection initializes a new DockerCreateConnection instance.
@@ -53,14 +62,24 @@ func NewDockerCreateConnection(log *zap.Logger, conn *config.TaskConnection) (ab
 }
 
 // Prepare sets up the Docker container configuration based on the provided task.
+// Environments of env-files are overridden by environments of the task.
 // Parameters:
 // - ctx: A context.Context instance for managing the lifecycle of the container.
 // - task: A Task instance containing the task configuration.
 // Returns:
 // - An error if the preparation fails, otherwise nil.
 func (d *DockerCreateConnection) Prepare(ctx context.Context, task *config.Task) error {
-	cmdCtx := command.NewCtx(ctx, task.Env, d.log)
+	env, err := d.environments(task.Env)
+	if err != nil {
+		return err
+	}
+	resources, err := d.resources()
+	if err != nil {
+		return err
+	}
+	cmdCtx := command.NewCtx(ctx, env, d.log)
 	d.ctx = ctx
+	d.result = common.ResultOf(ctx)
 	if d.conn.DockerConnection == "" {
 		d.log.Debug("No explicit docker connection specified, using default: `unix:///var/run/docker.sock`")
 		d.conn.DockerConnection = "unix:///var/run/docker.sock"
@@ -80,18 +99,22 @@ func (d *DockerCreateConnection) Prepare(ctx context.Context, task *config.Task)
 	d.containerConfig = &container.Config{
 		AttachStdout: true,
 		AttachStderr: true,
+		Tty:          d.conn.Tty,
 		Env:          environments,
 		WorkingDir:   task.WorkingDirectory,
 		User:         task.UserName,
 		Cmd:          cmd,
 		Image:        d.conn.ImageName,
 		Volumes:      volumes,
+		Labels:       d.conn.Labels,
 		Entrypoint:   []string{},
 		Shell:        []string{"/bin/sh", "-c"},
 	}
 	d.hostConfig = &container.HostConfig{
-		Binds: d.conn.Volumes,
-		// AutoRemove: true,
+		Binds:      d.conn.Volumes,
+		AutoRemove: d.conn.AutoRemove,
+		Privileged: d.conn.Privileged,
+		Resources:  resources,
 	}
 	endpointsConfig := make(map[string]*network.EndpointSettings)
 	for _, networkName := range d.conn.Networks {
@@ -103,6 +126,38 @@ func (d *DockerCreateConnection) Prepare(ctx context.Context, task *config.Task)
 	return nil
 }
 
+// environments reads env-files of the connection (in order) and applies environments of the task on them.
+func (d *DockerCreateConnection) environments(taskEnv map[string]string) (map[string]string, error) {
+	if len(d.conn.EnvFiles) == 0 {
+		return taskEnv, nil
+	}
+	env := make(map[string]string)
+	for _, file := range d.conn.EnvFiles {
+		fileEnv, err := godotenv.Read(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read env-file %q: %w", file, err)
+		}
+		maps.Copy(env, fileEnv)
+	}
+	maps.Copy(env, taskEnv)
+	return env, nil
+}
+
+// resources converts cpu and memory limits of the connection into container resources.
+func (d *DockerCreateConnection) resources() (container.Resources, error) {
+	resources := container.Resources{
+		NanoCPUs: int64(d.conn.CPUs * 1e9),
+	}
+	if d.conn.Memory != "" {
+		memory, err := units.RAMInBytes(d.conn.Memory)
+		if err != nil {
+			return resources, fmt.Errorf("invalid memory limit: %w", err)
+		}
+		resources.Memory = memory
+	}
+	return resources, nil
+}
+
 // Connect establishes a connection to the Docker daemon.
 // Returns:
 // - An error if the connection fails, otherwise nil.
@@ -118,15 +173,20 @@ func (d *DockerCreateConnection) Connect() error {
 	return nil
 }
 
-// Execute creates, starts, and logs the output of the Docker container.
+// Execute pulls the image (according to the pull policy), creates the container and attaches to it before starting it
+// (so output of short-lived containers removed by docker is not lost), reads its output
+// (demultiplexing stdout and stderr unless a tty is attached) and waits for it to exit.
+// The container is removed afterwards, or killed if the context is canceled and docker is removing it (auto-remove).
 // Returns:
 // - A byte slice containing the command output.
-// - An error if the execution fails, otherwise nil.
+// - An error if the execution fails or the container exits with a non-zero exit code (an *ExitError), otherwise nil.
 func (d *DockerCreateConnection) Execute() ([]byte, error) {
 	ctx := d.ctx
-	// Create the exec instance
+	if err := d.ensureImage(ctx); err != nil {
+		return nil, err
+	}
 
-	exec, err := d.cli.ContainerCreate(
+	created, err := d.cli.ContainerCreate(
 		ctx,
 		d.containerConfig,
 		d.hostConfig,
@@ -137,79 +197,120 @@ func (d *DockerCreateConnection) Execute() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	log := d.log.With(zap.String("container", created.ID))
+	log.Debug("container created", zap.Strings("warnings", created.Warnings))
+	defer d.cleanup(log, created.ID)
 
-	d.log.Debug("container created", zap.Any("response", exec), zap.Strings("warnings", exec.Warnings))
+	// the wait condition must be registered before the container is started, otherwise a fast exit can be missed
+	condition := container.WaitConditionNextExit
+	if d.conn.AutoRemove {
+		condition = container.WaitConditionRemoved
+	}
+	waitCh, waitErrCh := d.cli.ContainerWait(ctx, created.ID, condition)
 
-	defer helpers.WarnOnErrIgnored(
-		d.log,
-		func() error {
-			return d.cli.ContainerRemove(ctx, exec.ID,
-				container.RemoveOptions{
-					Force: true,
-				},
-			)
+	// attached before the start, like `docker run`, logs of auto-removed containers may be gone once they are requested
+	attached, err := d.cli.ContainerAttach(
+		ctx,
+		created.ID,
+		container.AttachOptions{
+			Stream: true,
+			Stdout: true,
+			Stderr: true,
 		},
-		"cannot remove the container",
 	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot attach to the container: %w", err)
+	}
+	defer attached.Close()
 
-	for {
-		err = d.cli.ContainerStart(
-			ctx,
-			exec.ID,
-			container.StartOptions{},
-		)
-
-		if err == nil {
-			break
-		}
+	if err := d.cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return nil, err
 	}
+	log.Debug("container started")
 
-	d.log.Debug("container started", zap.Any("container", exec))
+	output, err := copyOutput(attached.Reader, d.conn.Tty, d.result)
+	log.Debug("output of the container is fetched", zap.Int("bytes", len(output)))
+	if err != nil {
+		log.Debug("copy of std is failed", zap.Int("until-err", len(output)), zap.Error(err))
+		return output, err
+	}
 
-	for {
-		_, err = d.cli.ContainerStats(
-			ctx,
-			exec.ID,
-			false,
-		)
+	select {
+	case err := <-waitErrCh:
+		return output, fmt.Errorf("cannot wait for the container: %w", err)
+	case status := <-waitCh:
+		if status.Error != nil {
+			return output, fmt.Errorf("cannot wait for the container: %s", status.Error.Message)
+		}
+		if status.StatusCode != 0 {
+			err := &ExitError{Code: int(status.StatusCode)}
+			log.Warn("command execution failed", zap.String("output", strings.TrimSpace(string(output))), zap.Error(err))
+			return output, err
+		}
+	}
+	return output, nil
+}
 
+// ensureImage pulls the image of the container according to the pull policy (defaults to missing).
+func (d *DockerCreateConnection) ensureImage(ctx context.Context) error {
+	switch d.conn.PullPolicy {
+	case config.PullNever:
+		return nil
+	case config.PullAlways:
+	default:
+		_, err := d.cli.ImageInspect(ctx, d.conn.ImageName)
 		if err == nil {
-			break
+			return nil
+		}
+		if !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("cannot inspect the image: %w", err)
 		}
 	}
-
-	d.log.Debug("container started", zap.Any("container", exec))
-	// Attach to the exec instance
-	resp, err := d.cli.ContainerLogs(
-		ctx,
-		exec.ID,
-		container.LogsOptions{
-			ShowStdout: true,
-			ShowStderr: true,
-			Follow:     false,
-			Details:    true,
-		},
-	)
+	d.log.Debug("pulling the image", zap.String("image", d.conn.ImageName))
+	progress, err := d.cli.ImagePull(ctx, d.conn.ImageName, image.PullOptions{})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("cannot pull the image: %w", err)
 	}
 	defer helpers.WarnOnErrIgnored(
 		d.log,
-		func() error {
-			return resp.Close()
-		},
-		"cannot close the container's logs",
+		progress.Close,
+		"cannot close the pull progress",
 	)
+	// the pull is finished once its progress stream ends, errors are reported inside the stream
+	if err := jsonmessage.DisplayJSONMessagesStream(progress, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("cannot pull the image: %w", err)
+	}
+	return nil
+}
 
-	writer := bytes.NewBuffer([]byte{})
-	// Print the command output
-	wrote, err := io.Copy(writer, resp)
-	d.log.Debug("output of stdout is fetched", zap.Int64("bytes", wrote))
-	if err != nil {
-		d.log.Debug("copy of std is failed", zap.Int64("until-err", wrote), zap.Error(err))
-		return writer.Bytes(), err
+// cleanup removes the container, its context is detached from the task so canceled tasks are cleaned up as well.
+func (d *DockerCreateConnection) cleanup(log *zap.Logger, id string) {
+	ctx := context.WithoutCancel(d.ctx)
+	if !d.conn.AutoRemove {
+		helpers.WarnOnErrIgnored(
+			log,
+			func() error {
+				return d.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+			},
+			"cannot remove the container",
+		)
+		return
+	}
+	if d.ctx.Err() == nil {
+		return
 	}
-	return writer.Bytes(), nil
+	// docker removes the container once it is stopped
+	helpers.WarnOnErrIgnored(
+		log,
+		func() error {
+			err := d.cli.ContainerKill(ctx, id, "KILL")
+			if cerrdefs.IsNotFound(err) || cerrdefs.IsConflict(err) {
+				return nil
+			}
+			return err
+		},
+		"cannot kill the container",
+	)
 }
 
 // Disconnect closes the connection to the Docker daemon.
@@ -0,0 +1,144 @@
+package connection_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	connection "github.com/fmotalleb/crontab-go/core/cmd_connection"
+	"github.com/fmotalleb/crontab-go/core/common"
+)
+
+func create(t *testing.T, conn *config.TaskConnection, task *config.Task) (*common.Result, []byte, error) {
+	t.Helper()
+	con := connection.Get(conn, zap.NewNop())
+	ctx, res := common.WithResult(context.Background())
+	if err := con.Prepare(ctx, task); err != nil {
+		return res, nil, err
+	}
+	assert.NoError(t, con.Connect())
+	defer func() {
+		assert.NoError(t, con.Disconnect())
+	}()
+	out, err := con.Execute()
+	return res, out, err
+}
+
+func TestDockerCreate_Lifecycle(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.images["alpine:3"] = true
+	fake.stdout = "migrated\n"
+	fake.stderr = "slow query\n"
+	envFile := filepath.Join(t.TempDir(), "app.env")
+	assert.NoError(t, os.WriteFile(envFile, []byte("DB_HOST=db\nDB_USER=app\n"), 0o600))
+
+	res, out, err := create(t,
+		&config.TaskConnection{
+			DockerConnection: fake.Host(),
+			ImageName:        "alpine:3",
+			CPUs:             1.5,
+			Memory:           "256m",
+			Labels:           map[string]string{"app": "migrations"},
+			EnvFiles:         []string{envFile},
+		},
+		&config.Task{Command: "migrate", Env: map[string]string{"DB_USER": "admin"}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "migrated\nslow query\n", string(out))
+	assert.Equal(t, "migrated\n", res.Stdout)
+	assert.Equal(t, "slow query\n", res.Stderr)
+	assert.Equal(t, 0, len(fake.pulled()))
+
+	created := fake.createdContainers()
+	assert.Equal(t, 1, len(created))
+	assert.Equal(t, map[string]string{"app": "migrations"}, created[0].Labels)
+	assert.Equal(t, int64(1_500_000_000), created[0].HostConfig.NanoCPUs)
+	assert.Equal(t, int64(256*1024*1024), created[0].HostConfig.Memory)
+	assert.True(t, slices.Contains(created[0].Env, "DB_HOST=db"))
+	assert.True(t, slices.Contains(created[0].Env, "DB_USER=admin"))
+	assert.Equal(t, []string{"container-1"}, fake.removedContainers())
+}
+
+func TestDockerCreate_AutoRemoveKeepsOutput(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.images["alpine:3"] = true
+	fake.stdout = "done\n"
+
+	_, out, err := create(t,
+		&config.TaskConnection{
+			DockerConnection: fake.Host(),
+			ImageName:        "alpine:3",
+			AutoRemove:       true,
+			Tty:              true,
+		},
+		&config.Task{Command: "true"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "done\n", string(out))
+	assert.True(t, fake.createdContainers()[0].HostConfig.AutoRemove)
+}
+
+func TestDockerCreate_ExitCode(t *testing.T) {
+	fake := newFakeDocker(t)
+	fake.images["alpine:3"] = true
+	fake.stdout = "oops\n"
+	fake.exitCode = 2
+
+	res, out, err := create(t, &config.TaskConnection{
+		DockerConnection: fake.Host(),
+		ImageName:        "alpine:3",
+		Tty:              true,
+		AutoRemove:       true,
+	}, &config.Task{Command: "false"})
+	var exitErr *connection.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 2, exitErr.ExitCode())
+	assert.Equal(t, "oops\n", string(out))
+
+	res.SetError(err)
+	assert.Equal(t, 2, res.ExitCode)
+	created := fake.createdContainers()
+	assert.True(t, created[0].Tty)
+	assert.True(t, created[0].HostConfig.AutoRemove)
+	// auto removed containers are removed by docker
+	assert.Equal(t, 0, len(fake.removedContainers()))
+}
+
+func TestDockerCreate_PullPolicy(t *testing.T) {
+	tests := []struct {
+		policy  config.PullPolicy
+		present bool
+		pulled  bool
+		fails   bool
+	}{
+		{policy: "", present: true, pulled: false},
+		{policy: config.PullMissing, present: false, pulled: true},
+		{policy: config.PullAlways, present: true, pulled: true},
+		{policy: config.PullNever, present: true, pulled: false},
+		{policy: config.PullNever, present: false, pulled: false, fails: true},
+	}
+	for _, tt := range tests {
+		fake := newFakeDocker(t)
+		fake.images["alpine:3"] = tt.present
+
+		_, _, err := create(t, &config.TaskConnection{
+			DockerConnection: fake.Host(),
+			ImageName:        "alpine:3",
+			PullPolicy:       tt.policy,
+		}, &config.Task{Command: "true"})
+		assert.Equal(t, tt.fails, err != nil, "policy %q, present %v: %v", tt.policy, tt.present, err)
+		assert.Equal(t, tt.pulled, len(fake.pulled()) == 1, "policy %q, present %v", tt.policy, tt.present)
+	}
+}
+
+func TestDockerCreate_InvalidMemory(t *testing.T) {
+	_, _, err := create(t, &config.TaskConnection{ImageName: "alpine:3", Memory: "lots"}, &config.Task{Command: "true"})
+	assert.Error(t, err)
+}
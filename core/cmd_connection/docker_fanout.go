@@ -0,0 +1,116 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+const (
+	FanOutMetricName = "fan_out_executions"
+	FanOutMetricHelp = "amount of commands executed in containers matched by the label of docker connections"
+)
+
+// target is a container that the command is executed in.
+type target struct {
+	id   string
+	name string
+}
+
+func targetOf(c container.Summary) target {
+	t := target{id: c.ID, name: c.ID}
+	if len(c.Names) > 0 {
+		t.name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	return t
+}
+
+// execution is the outcome of the command in a single container.
+type execution struct {
+	output []byte
+	result *common.Result
+	err    error
+}
+
+// fanOut executes the command in every target (concurrently unless sequential is set),
+// outputs of containers are combined in the order of targets and the success policy decides the outcome.
+func (d *DockerAttachConnection) fanOut(targets []target) ([]byte, error) {
+	executions := make([]execution, len(targets))
+	run := func(i int) {
+		res := &common.Result{}
+		output, err := d.execIn(targets[i].id, res)
+		executions[i] = execution{output: output, result: res, err: err}
+	}
+	if d.conn.Sequential {
+		for i := range targets {
+			run(i)
+		}
+	} else {
+		wg := new(sync.WaitGroup)
+		for i := range targets {
+			wg.Go(func() { run(i) })
+		}
+		wg.Wait()
+	}
+
+	var output []byte
+	var errs []error
+	for i, exec := range executions {
+		t := targets[i]
+		output = append(output, exec.output...)
+		d.result.AppendStreams([]byte(exec.result.Stdout), []byte(exec.result.Stderr))
+		status := "done"
+		if exec.err != nil {
+			status = "failed"
+			errs = append(errs, fmt.Errorf("container %s: %w", t.name, exec.err))
+		}
+		d.log.Info(
+			"command executed in container",
+			zap.String("container", t.name),
+			zap.String("status", status),
+			zap.Error(exec.err),
+		)
+		global.IncMetric(
+			FanOutMetricName,
+			FanOutMetricHelp,
+			prometheus.Labels{
+				"label":     d.conn.ContainerLabel,
+				"container": t.name,
+				"status":    status,
+			},
+		)
+	}
+
+	succeeded := len(targets) - len(errs)
+	if required := d.required(len(targets)); succeeded < required {
+		return output, fmt.Errorf(
+			"command succeeded in %d of %d containers, %d required: %w",
+			succeeded, len(targets), required, errors.Join(errs...),
+		)
+	}
+	return output, nil
+}
+
+// required returns the number of containers that the command must succeed in according to the success policy.
+func (d *DockerAttachConnection) required(total int) int {
+	switch d.conn.SuccessPolicy {
+	case config.SuccessAny:
+		return 1
+	case config.SuccessQuorum:
+		if d.conn.Quorum != 0 {
+			return int(d.conn.Quorum)
+		}
+		return total/2 + 1
+	default:
+		return total
+	}
+}
@@ -0,0 +1,233 @@
+package connection_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fakeDocker is a minimal fake of the Docker Engine API, serving the endpoints used by docker connections.
+type fakeDocker struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// containers listed by the label filter
+	containers []string
+	// output and exit code of exec instances
+	stdout   string
+	stderr   string
+	exitCode int
+	// exitCodes overrides the exit code of exec instances per container
+	exitCodes map[string]int
+	// execs holds exec instances created per container
+	execs map[string][]container.ExecOptions
+	// images present on the fake, pulled images are added to it
+	images map[string]bool
+	pulls  []string
+	// created holds containers created by docker-create connections by their id
+	created map[string]container.CreateRequest
+	// started is closed once the container is started, attached streams wait for it
+	started map[string]chan struct{}
+	removed []string
+}
+
+func newFakeDocker(t *testing.T) *fakeDocker {
+	t.Helper()
+	fake := &fakeDocker{
+		execs:   map[string][]container.ExecOptions{},
+		images:  map[string]bool{},
+		created: map[string]container.CreateRequest{},
+		started: map[string]chan struct{}{},
+	}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+// Host is the docker connection string of the fake.
+func (f *fakeDocker) Host() string {
+	return "tcp://" + f.Listener.Addr().String()
+}
+
+func (f *fakeDocker) execsOf(container string) []container.ExecOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.execs[container]
+}
+
+func (f *fakeDocker) pulled() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pulls
+}
+
+// createdContainers lists configurations of created containers, there is no order between them.
+func (f *fakeDocker) createdContainers() []container.CreateRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := []container.CreateRequest{}
+	for _, req := range f.created {
+		list = append(list, req)
+	}
+	return list
+}
+
+func (f *fakeDocker) removedContainers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.removed
+}
+
+func (f *fakeDocker) serve(w http.ResponseWriter, r *http.Request) {
+	path := apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "/_ping":
+		w.Header().Set("Api-Version", "1.47")
+		_, _ = w.Write([]byte("OK"))
+	case path == "/containers/json":
+		list := []container.Summary{}
+		for _, id := range f.containers {
+			list = append(list, container.Summary{ID: id, Names: []string{"/" + id}})
+		}
+		writeJSON(w, list)
+	case len(parts) >= 3 && parts[0] == "images" && parts[len(parts)-1] == "json":
+		f.mu.Lock()
+		found := f.images[strings.Join(parts[1:len(parts)-1], "/")]
+		f.mu.Unlock()
+		if !found {
+			notFound(w, "no such image")
+			return
+		}
+		writeJSON(w, image.InspectResponse{ID: "sha256:fake"})
+	case path == "/images/create":
+		ref := strings.TrimPrefix(r.URL.Query().Get("fromImage"), "docker.io/library/") + ":" + r.URL.Query().Get("tag")
+		f.mu.Lock()
+		f.pulls = append(f.pulls, ref)
+		f.images[ref] = true
+		f.mu.Unlock()
+		writeJSON(w, jsonmessage.JSONMessage{Status: "Downloaded newer image for " + ref})
+	case path == "/containers/create":
+		var req container.CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.images[req.Image] {
+			notFound(w, "no such image: "+req.Image)
+			return
+		}
+		id := fmt.Sprintf("container-%d", len(f.created)+1)
+		f.created[id] = req
+		f.started[id] = make(chan struct{})
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, container.CreateResponse{ID: id})
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "start":
+		f.mu.Lock()
+		close(f.started[parts[1]])
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "wait":
+		writeJSON(w, container.WaitResponse{StatusCode: int64(f.exitCode)})
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "attach":
+		// output is only streamed once the container is started, logs of auto-removed containers are never served
+		f.mu.Lock()
+		tty, started := f.created[parts[1]].Tty, f.started[parts[1]]
+		f.mu.Unlock()
+		if started == nil {
+			notFound(w, "no such container: "+parts[1])
+			return
+		}
+		f.hijackAfter(w, tty, started)
+	case len(parts) == 2 && parts[0] == "containers" && r.Method == http.MethodDelete:
+		f.mu.Lock()
+		f.removed = append(f.removed, parts[1])
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "exec":
+		var opts container.ExecOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.execs[parts[1]] = append(f.execs[parts[1]], opts)
+		id := fmt.Sprintf("%s-exec-%d", parts[1], len(f.execs[parts[1]]))
+		f.mu.Unlock()
+		writeJSON(w, container.ExecCreateResponse{ID: id})
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "start":
+		var opts container.ExecStartOptions
+		_ = json.NewDecoder(r.Body).Decode(&opts)
+		f.hijack(w, opts.Tty)
+	case len(parts) == 3 && parts[0] == "exec" && parts[2] == "json":
+		exitCode := f.exitCode
+		if code, ok := f.exitCodes[strings.Split(parts[1], "-exec-")[0]]; ok {
+			exitCode = code
+		}
+		writeJSON(w, container.ExecInspect{ExecID: parts[1], ExitCode: exitCode})
+	default:
+		http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+// hijack upgrades the connection and streams the output, multiplexed unless a tty is attached.
+func (f *fakeDocker) hijack(w http.ResponseWriter, tty bool) {
+	f.hijackAfter(w, tty, nil)
+}
+
+// hijackAfter upgrades the connection and streams the output once ready is closed (if given).
+func (f *fakeDocker) hijackAfter(w http.ResponseWriter, tty bool, ready <-chan struct{}) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	contentType := "application/vnd.docker.multiplexed-stream"
+	if tty {
+		contentType = "application/vnd.docker.raw-stream"
+	}
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 101 UPGRADED\r\nContent-Type: %s\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n", contentType)
+	_ = buf.Flush()
+	if ready != nil {
+		<-ready
+	}
+	f.writeOutput(buf, tty)
+	_ = buf.Flush()
+}
+
+// writeOutput writes stdout and stderr, multiplexed unless a tty is attached.
+func (f *fakeDocker) writeOutput(w io.Writer, tty bool) {
+	if tty {
+		_, _ = io.WriteString(w, f.stdout+f.stderr)
+		return
+	}
+	_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(f.stdout))
+	_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte(f.stderr))
+}
+
+func notFound(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -1,19 +1,25 @@
 package connection
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
 	credential "github.com/fmotalleb/crontab-go/core/os_credential"
+	"github.com/fmotalleb/crontab-go/core/process"
 )
 
 func init() {
@@ -22,8 +28,13 @@ func init() {
 
 // Local represents a local command connection.
 type Local struct {
-	log *zap.Logger
-	cmd *exec.Cmd
+	log    *zap.Logger
+	ctx    context.Context
+	cmd    *exec.Cmd
+	result *common.Result
+
+	stopGrace time.Duration
+	stoppedAt time.Time
 }
 
 // NewLocalCMDConn creates a new instance of Local command connection.
@@ -44,6 +55,7 @@ func NewLocalCMDConn(log *zap.Logger, cfg *config.TaskConnection) (abstraction.C
 // It returns an error if the preparation fails.
 func (l *Local) Prepare(ctx context.Context, task *config.Task) error {
 	cmdCtx := command.NewCtx(ctx, task.Env, l.log)
+	l.result = common.ResultOf(ctx)
 	workingDir := task.WorkingDirectory
 	if workingDir == "" {
 		var e error
@@ -66,6 +78,18 @@ func (l *Local) Prepare(ctx context.Context, task *config.Task) error {
 		zap.Strings("shell_args", commandArg),
 	)
 	credential.SetUser(l.log, l.cmd, task.UserName, task.GroupName)
+	// once canceled (timeout or shutdown) the stop signal is sent to the process group of the command,
+	// the group is killed if it does not exit within the stop grace
+	process.SetGroup(l.cmd)
+	stopSignal := stopSignalOf(task)
+	l.stopGrace = cmp.Or(task.StopGrace, process.KillDelay)
+	l.cmd.Cancel = func() error {
+		l.stoppedAt = time.Now()
+		l.log.Debug("sending stop signal to the command", zap.Stringer("signal", stopSignal))
+		return process.SignalGroup(l.cmd.Process, stopSignal)
+	}
+	l.cmd.WaitDelay = l.stopGrace
+	l.ctx = ctx
 	l.cmd.Env = environ
 	l.cmd.Dir = workingDir
 
@@ -88,17 +112,23 @@ func (l *Local) Disconnect() error {
 }
 
 // Execute executes the command and returns the output.
-// It captures the command's standard output and standard error.
+// It captures the command's standard output and standard error,
+// streams are also recorded separately in the result of the task.
 // It returns the output and an error, if any.
 func (l *Local) Execute() ([]byte, error) {
-	var res bytes.Buffer
-	l.cmd.Stdout = &res
-	l.cmd.Stderr = &res
+	res := common.NewOutputBuffer()
+	stdout, stderr := common.NewOutputBuffer(), common.NewOutputBuffer()
+	l.cmd.Stdout = io.MultiWriter(stdout, res)
+	l.cmd.Stderr = io.MultiWriter(stderr, res)
+	defer func() {
+		l.result.AppendStreams(stdout.Bytes(), stderr.Bytes())
+	}()
+	defer l.killGroup()
 	log := l.log.Named("execute")
-	if err := l.cmd.Start(); err != nil {
+	if err := process.Start(l.cmd); err != nil {
 		log.Warn("failed to start the command", zap.Error(err))
 		return []byte{}, err
-	} else if err := l.cmd.Wait(); err != nil {
+	} else if err := process.Wait(l.cmd); err != nil {
 		output := res.Bytes()
 		log.Warn("command execution failed", zap.String("output", strings.TrimSpace(res.String())), zap.Error(err))
 		return output, err
@@ -106,3 +136,28 @@ func (l *Local) Execute() ([]byte, error) {
 	l.log.Debug("command output", zap.String("output", strings.TrimSpace(res.String())))
 	return res.Bytes(), nil
 }
+
+// killGroup kills what is left of the process group of a canceled command once its stop grace is over,
+// so children of the shell (e.g. `pg_dump` in a pipeline) do not outlive the command.
+func (l *Local) killGroup() {
+	if l.ctx.Err() == nil || l.cmd.Process == nil {
+		return
+	}
+	deadline := time.Now()
+	if !l.stoppedAt.IsZero() {
+		deadline = l.stoppedAt.Add(l.stopGrace)
+	}
+	if err := process.KillGroup(l.cmd.Process, deadline); err != nil {
+		l.log.Warn("failed to kill the process group of the command", zap.Error(err))
+	}
+}
+
+// stopSignalOf returns the stop signal of the task, falling back to the global stop signal.
+func stopSignalOf(task *config.Task) syscall.Signal {
+	if task.StopSignal != "" {
+		if sig, err := process.ParseSignal(task.StopSignal); err == nil {
+			return sig
+		}
+	}
+	return global.Get[process.StopSignal]().Signal()
+}
@@ -1,15 +1,23 @@
 package common
 
+import "sync"
+
 type Cancelable struct {
+	mu     sync.Mutex
 	cancel func()
 }
 
 func (c *Cancelable) SetCancel(cancel func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.cancel = cancel
 }
 
 func (c *Cancelable) Cancel() {
-	if c.cancel != nil {
-		c.cancel()
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
 	}
 }
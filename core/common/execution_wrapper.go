@@ -2,12 +2,17 @@ package common
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/fmotalleb/go-tools/log"
 	"github.com/sethvargo/go-retry"
 	"go.uber.org/zap"
 )
 
+// ErrSkipped is returned by tasks that were not executed because their `when` condition did not hold.
+var ErrSkipped = errors.New("task skipped, when condition did not hold")
+
 type Action interface {
 	Do(ctx context.Context) (e error)
 }
@@ -19,6 +24,7 @@ type Executable struct {
 }
 
 func (rh *Executable) forceRetry(ctx context.Context) error {
+	ResultOf(ctx).StartAttempt()
 	err := rh.Do(ctx)
 	if err != nil {
 		return retry.RetryableError(err)
@@ -28,7 +34,12 @@ func (rh *Executable) forceRetry(ctx context.Context) error {
 
 // Execute implements abstraction.Executable.
 func (rh *Executable) Execute(ctx context.Context) error {
+	res := ResultOf(ctx)
+	start := time.Now()
 	err := rh.ExecuteRetry(ctx, rh.forceRetry)
+	res.Duration = time.Since(start)
+	res.SetError(err)
+	ctx = WithTriggerResult(ctx, res)
 	if err == nil {
 		errs := rh.DoDoneHooks(ctx)
 		if len(errs) != 0 {
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -65,7 +66,9 @@ func (h *Hooked) DoFailHooks(ctx context.Context) []error {
 func executeTasks(ctx context.Context, tasks []abstraction.Executable) []error {
 	errs := []error{}
 	for _, exe := range tasks {
-		if err := exe.Execute(ctx); err != nil {
+		// hooks must not overwrite the result of the task that triggered them
+		hookCtx, _ := WithResult(ctx)
+		if err := exe.Execute(hookCtx); err != nil && !errors.Is(err, ErrSkipped) {
 			errs = append(errs, err)
 		}
 	}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+// DefaultMaxOutput limits the output (and response body) captured per task unless configured otherwise.
+const DefaultMaxOutput = 1 << 20
+
+// TruncatedMark prefixes captured output that exceeded the limit.
+const TruncatedMark = "[truncated] "
+
+// MaxOutput is the amount of bytes of output (and response body) captured per task,
+// the zero value falls back to DefaultMaxOutput.
+type MaxOutput uint
+
+// Limit returns the limit, or the default one if it is not set.
+func (m MaxOutput) Limit() int {
+	if m == 0 {
+		return DefaultMaxOutput
+	}
+	return int(m)
+}
+
+// OutputLimit returns the configured limit of captured output.
+func OutputLimit() int {
+	return global.Get[MaxOutput]().Limit()
+}
+
+// OutputBuffer keeps the tail of written data (where errors usually are) up to the configured limit.
+// It is safe to be written by stdout and stderr copiers at the same time.
+type OutputBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func NewOutputBuffer() *OutputBuffer {
+	return &OutputBuffer{limit: OutputLimit()}
+}
+
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	// dropping the head is deferred until the buffer doubles, so it is not copied on every write
+	if len(b.buf) > 2*b.limit {
+		b.compact()
+	}
+	return len(p), nil
+}
+
+func (b *OutputBuffer) compact() {
+	if len(b.buf) <= b.limit {
+		return
+	}
+	start := len(b.buf) - b.limit
+	for start < len(b.buf) && !utf8.RuneStart(b.buf[start]) {
+		start++
+	}
+	b.buf = append([]byte(nil), b.buf[start:]...)
+	b.truncated = true
+}
+
+// Bytes returns the captured tail, marked with TruncatedMark if anything was dropped.
+func (b *OutputBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.compact()
+	if !b.truncated {
+		return append([]byte(nil), b.buf...)
+	}
+	return append([]byte(TruncatedMark), b.buf...)
+}
+
+func (b *OutputBuffer) String() string {
+	return string(b.Bytes())
+}
+
+// truncateOutput keeps the tail of output up to the configured limit, marked with TruncatedMark if anything was dropped.
+func truncateOutput(output string) string {
+	limit := OutputLimit()
+	if len(output) <= limit {
+		return output
+	}
+	start := len(output) - limit
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return TruncatedMark + output[start:]
+}
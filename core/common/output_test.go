@@ -0,0 +1,50 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+func limitOutput(t *testing.T, limit uint) {
+	t.Helper()
+	global.Put(common.MaxOutput(limit))
+	t.Cleanup(func() {
+		global.Put(common.MaxOutput(0))
+	})
+}
+
+func TestMaxOutput_Default(t *testing.T) {
+	assert.Equal(t, common.DefaultMaxOutput, common.MaxOutput(0).Limit())
+	assert.Equal(t, 10, common.MaxOutput(10).Limit())
+}
+
+func TestOutputBuffer_KeepsTail(t *testing.T) {
+	limitOutput(t, 8)
+	buf := common.NewOutputBuffer()
+	_, err := buf.Write([]byte("short"))
+	assert.NoError(t, err)
+	assert.Equal(t, "short", buf.String())
+
+	for _, chunk := range []string{"0123456789", "abcdefghij", "klmn"} {
+		n, err := buf.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, common.TruncatedMark+"ghijklmn", buf.String())
+}
+
+func TestResult_AppendOutputLimit(t *testing.T) {
+	limitOutput(t, 4)
+	res := &common.Result{}
+	res.AppendOutput([]byte("ab"))
+	assert.Equal(t, "ab", res.Output)
+	res.AppendOutput([]byte("cdef"))
+	assert.Equal(t, common.TruncatedMark+"cdef", res.Output)
+	res.AppendStreams([]byte("123456"), []byte("xy"))
+	assert.Equal(t, common.TruncatedMark+"3456", res.Stdout)
+	assert.Equal(t, "xy", res.Stderr)
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/fmotalleb/crontab-go/ctxutils"
+)
+
+// Result holds the captured outcome of a task.
+// It is exposed to the hooks of the task as `{{ .Result.<Field> }}`.
+type Result struct {
+	// Output is the combined stdout and stderr of commands
+	Output     string
+	Stdout     string
+	Stderr     string
+	ExitCode   int
+	StatusCode int
+	Response   string
+	Error      string
+	TimedOut   bool
+	Attempt    int
+	Duration   time.Duration
+}
+
+// WithResult attaches a fresh result holder to the context, tasks executed
+// using the returned context will write their outcome into it.
+func WithResult(ctx context.Context) (context.Context, *Result) {
+	res := &Result{}
+	return context.WithValue(ctx, ctxutils.TaskResult, res), res
+}
+
+// ResultOf returns the result holder of the context,
+// if no holder is attached a detached one is returned so callers can write safely.
+func ResultOf(ctx context.Context) *Result {
+	if res, ok := ctx.Value(ctxutils.TaskResult).(*Result); ok {
+		return res
+	}
+	return &Result{}
+}
+
+// WithTriggerResult exposes a snapshot of the result to the templates of hooks executed using the returned context.
+func WithTriggerResult(ctx context.Context, res *Result) context.Context {
+	return context.WithValue(ctx, ctxutils.Result, *res)
+}
+
+// StartAttempt clears the result of the previous attempt and increases the attempt counter.
+func (r *Result) StartAttempt() {
+	*r = Result{Attempt: r.Attempt + 1}
+}
+
+// AppendOutput appends the given output to the captured output, only the tail is kept once it exceeds the output limit.
+func (r *Result) AppendOutput(out []byte) {
+	r.Output = truncateOutput(r.Output + string(out))
+}
+
+// AppendStreams appends separately captured stdout and stderr, only their tails are kept once they exceed the output limit.
+func (r *Result) AppendStreams(stdout []byte, stderr []byte) {
+	r.Stdout = truncateOutput(r.Stdout + string(stdout))
+	r.Stderr = truncateOutput(r.Stderr + string(stderr))
+}
+
+// SetError records the error and the exit code carried by it, if err does not carry any exit code -1 is used.
+func (r *Result) SetError(err error) {
+	if err == nil {
+		return
+	}
+	r.Error = err.Error()
+	r.TimedOut = errors.Is(err, ErrTimedOut)
+	var coded interface{ ExitCode() int }
+	if errors.As(err, &coded) {
+		r.ExitCode = coded.ExitCode()
+		return
+	}
+	r.ExitCode = -1
+}
+
+// Export writes the result into the variable table using `<prefix>_<field>` keys.
+// `<prefix>_output` holds the combined output, `<prefix>_stdout` and `<prefix>_stderr` hold the separated streams of commands.
+func (r *Result) Export(prefix string, vars map[string]string) {
+	vars[prefix+"_output"] = r.Output
+	vars[prefix+"_stdout"] = r.Stdout
+	vars[prefix+"_stderr"] = r.Stderr
+	vars[prefix+"_exit_code"] = strconv.Itoa(r.ExitCode)
+	vars[prefix+"_status"] = strconv.Itoa(r.StatusCode)
+	vars[prefix+"_response"] = r.Response
+	vars[prefix+"_error"] = r.Error
+	vars[prefix+"_timed_out"] = strconv.FormatBool(r.TimedOut)
+}
@@ -0,0 +1,129 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/ctxutils"
+)
+
+func TestResultOf_Detached(t *testing.T) {
+	res := common.ResultOf(t.Context())
+	res.AppendOutput([]byte("ignored"))
+	assert.Equal(t, "", common.ResultOf(t.Context()).Output)
+}
+
+func TestResultOf_Attached(t *testing.T) {
+	ctx, res := common.WithResult(t.Context())
+	common.ResultOf(ctx).AppendOutput([]byte("ok"))
+	assert.Equal(t, "ok", res.Output)
+}
+
+func TestResult_SetError(t *testing.T) {
+	res := &common.Result{}
+	res.SetError(nil)
+	assert.Equal(t, 0, res.ExitCode)
+
+	res.SetError(errors.New("unknown"))
+	assert.Equal(t, -1, res.ExitCode)
+	assert.Equal(t, "unknown", res.Error)
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	res.SetError(err)
+	assert.Equal(t, 3, res.ExitCode)
+	assert.False(t, res.TimedOut)
+
+	res.SetError(fmt.Errorf("%w: %w", common.ErrTimedOut, err))
+	assert.Equal(t, 3, res.ExitCode)
+	assert.True(t, res.TimedOut)
+}
+
+func TestResult_Export(t *testing.T) {
+	res := &common.Result{Output: "out", Stdout: "std", Stderr: "err", StatusCode: 200, Response: "body"}
+	vars := map[string]string{}
+	res.Export("step_1", vars)
+	assert.Equal(t, map[string]string{
+		"step_1_output":    "out",
+		"step_1_stdout":    "std",
+		"step_1_stderr":    "err",
+		"step_1_exit_code": "0",
+		"step_1_status":    "200",
+		"step_1_response":  "body",
+		"step_1_error":     "",
+		"step_1_timed_out": "false",
+	}, vars)
+}
+
+func TestResult_StartAttempt(t *testing.T) {
+	res := &common.Result{Output: "old", ExitCode: 2, Attempt: 1}
+	res.StartAttempt()
+	assert.Equal(t, common.Result{Attempt: 2}, *res)
+}
+
+type flakyAction struct {
+	failures int
+	calls    int
+}
+
+func (a *flakyAction) Do(ctx context.Context) error {
+	a.calls++
+	common.ResultOf(ctx).AppendOutput([]byte("call"))
+	if a.calls <= a.failures {
+		return errors.New("flaky")
+	}
+	return nil
+}
+
+type resultRecorder struct {
+	common.Cancelable
+	common.Hooked
+	seen []common.Result
+}
+
+func (r *resultRecorder) Execute(ctx context.Context) error {
+	if res, ok := ctx.Value(ctxutils.Result).(common.Result); ok {
+		r.seen = append(r.seen, res)
+	}
+	return nil
+}
+
+func TestExecutable_ExposesResultToHooks(t *testing.T) {
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	action := &flakyAction{failures: 1}
+	exe := &common.Executable{Action: action}
+	exe.SetMaxRetry(1)
+	exe.SetDelayModifierFromString("const")
+	exe.SetRetryDelay(time.Millisecond)
+	done := &resultRecorder{}
+	exe.SetDoneHooks(ctx, []abstraction.Executable{done})
+
+	ctx, res := common.WithResult(ctx)
+	assert.NoError(t, exe.Execute(ctx))
+	assert.Equal(t, 2, res.Attempt)
+	assert.Equal(t, "call", res.Output)
+	assert.Equal(t, 1, len(done.seen))
+	assert.Equal(t, 2, done.seen[0].Attempt)
+	assert.True(t, done.seen[0].Duration > 0)
+}
+
+func TestExecutable_ExposesErrorToFailHooks(t *testing.T) {
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	exe := &common.Executable{Action: &flakyAction{failures: 5}}
+	exe.SetDelayModifierFromString("const")
+	exe.SetRetryDelay(time.Millisecond)
+	failed := &resultRecorder{}
+	exe.SetFailHooks(ctx, []abstraction.Executable{failed})
+
+	assert.Error(t, exe.Execute(ctx))
+	assert.Equal(t, 1, len(failed.seen))
+	assert.Equal(t, "flaky", failed.seen[0].Error)
+	assert.Equal(t, -1, failed.seen[0].ExitCode)
+}
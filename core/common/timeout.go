@@ -2,9 +2,14 @@ package common
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// ErrTimedOut is the cause of contexts returned by Timeout.ApplyTimeout once the timeout expires.
+var ErrTimedOut = errors.New("timed out")
+
 type Timeout struct {
 	timeout time.Duration
 }
@@ -15,7 +20,15 @@ func (t *Timeout) SetTimeout(timeout time.Duration) {
 
 func (t *Timeout) ApplyTimeout(ctx context.Context) (context.Context, func()) {
 	if t.timeout != 0 {
-		return context.WithTimeout(ctx, t.timeout)
+		return context.WithTimeoutCause(ctx, t.timeout, ErrTimedOut)
 	}
 	return context.WithCancel(ctx)
 }
+
+// TimeoutError marks the error as ErrTimedOut if ctx (returned by ApplyTimeout) timed out.
+func TimeoutError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrTimedOut) || !errors.Is(context.Cause(ctx), ErrTimedOut) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrTimedOut, err)
+}
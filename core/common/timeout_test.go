@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -32,3 +33,20 @@ func TestNoTimeoutApply(t *testing.T) {
 	assert.Equal(t, false, ok)
 	assert.NotEqual(t, nil, cancel)
 }
+
+func TestTimeoutError(t *testing.T) {
+	timeout := &Timeout{}
+	timeout.SetTimeout(time.Millisecond)
+	ctx, cancel := timeout.ApplyTimeout(t.Context())
+	defer cancel()
+	<-ctx.Done()
+	err := TimeoutError(ctx, errors.New("signal: terminated"))
+	assert.IsError(t, err, ErrTimedOut)
+	assert.Equal(t, "timed out: signal: terminated", err.Error())
+	assert.Equal(t, nil, TimeoutError(ctx, nil))
+
+	// canceled contexts are not timeouts
+	ctx, cancel = timeout.ApplyTimeout(t.Context())
+	cancel()
+	assert.False(t, errors.Is(TimeoutError(ctx, errors.New("canceled")), ErrTimedOut))
+}
@@ -0,0 +1,185 @@
+package discovery
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/jobs"
+)
+
+// Source is the source of jobs discovered from labels of containers.
+const Source = "docker-discovery"
+
+// reconnectDelay throttles reconnecting to docker after the event stream fails.
+const reconnectDelay = 5 * time.Second
+
+// Applier runs the discovered jobs, replacing the previously discovered ones.
+type Applier interface {
+	ApplySource(source string, jobs []*config.JobConfig) (jobs.Changes, error)
+}
+
+// Docker discovers jobs from labels of running containers, jobs are registered once their container starts
+// and removed once it stops.
+type Docker struct {
+	connection string
+	prefix     string
+	applier    Applier
+	log        *zap.Logger
+
+	mu         sync.Mutex
+	containers map[string][]*config.JobConfig
+}
+
+// NewDocker creates a discovery using the docker connection, prefix of labels defaults to DefaultPrefix.
+func NewDocker(connection string, prefix string, applier Applier, log *zap.Logger) *Docker {
+	return &Docker{
+		connection: connection,
+		prefix:     cmp.Or(prefix, DefaultPrefix),
+		applier:    applier,
+		log:        log.With(zap.String("connection", connection)),
+		containers: make(map[string][]*config.JobConfig),
+	}
+}
+
+// Run discovers jobs until ctx is done, running containers are listed on every (re)connection
+// and the event stream of containers keeps the discovered jobs up to date.
+func (d *Docker) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := d.connectAndListen(ctx); err != nil {
+			d.log.Warn("docker discovery failed, reconnecting", zap.Error(err), zap.Duration("delay", reconnectDelay))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (d *Docker) connectAndListen(c context.Context) error {
+	cli, err := client.NewClientWithOpts(
+		client.WithHost(d.connection),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	// subscribe before listing, so containers started in between are not missed
+	args := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("event", string(events.ActionStart)),
+		filters.Arg("event", string(events.ActionDie)),
+	)
+	msg, errs := cli.Events(ctx, events.ListOptions{Filters: args})
+
+	running, err := cli.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return err
+	}
+	d.sync(running)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case event := <-msg:
+			d.handle(event)
+		}
+	}
+}
+
+// sync replaces the discovered jobs with jobs of the running containers.
+func (d *Docker) sync(running []container.Summary) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clear(d.containers)
+	for _, c := range running {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		d.discover(Container{ID: c.ID, Name: name, Labels: c.Labels})
+	}
+	d.apply()
+}
+
+// handle registers jobs of started containers and removes jobs of stopped containers,
+// attributes of container events contain the name and labels of the container.
+func (d *Docker) handle(event events.Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch event.Action {
+	case events.ActionStart:
+		c := Container{ID: event.Actor.ID, Name: event.Actor.Attributes["name"], Labels: event.Actor.Attributes}
+		if !d.discover(c) {
+			return
+		}
+	case events.ActionDie:
+		if _, ok := d.containers[event.Actor.ID]; !ok {
+			return
+		}
+		delete(d.containers, event.Actor.ID)
+	default:
+		return
+	}
+	d.apply()
+}
+
+// discover records jobs declared by the container, it reports whether the container declares any valid job.
+func (d *Docker) discover(c Container) bool {
+	log := d.log.With(zap.String("container", c.Name))
+	found, err := JobsOf(d.connection, d.prefix, c)
+	if err != nil {
+		log.Warn("ignoring invalid jobs declared by labels", zap.Error(err))
+	}
+	valid := make([]*config.JobConfig, 0, len(found))
+	for _, job := range found {
+		if err := job.Validate(log.Named("Validator")); err != nil {
+			log.Warn("ignoring invalid job declared by labels", zap.String("job.name", job.Name), zap.Error(err))
+			continue
+		}
+		valid = append(valid, job)
+	}
+	if len(valid) == 0 {
+		return false
+	}
+	d.containers[c.ID] = valid
+	return true
+}
+
+// apply runs jobs of every known container, ordered by container id.
+func (d *Docker) apply() {
+	discovered := make([]*config.JobConfig, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.containers)) {
+		discovered = append(discovered, d.containers[id]...)
+	}
+	changes, err := d.applier.ApplySource(Source, discovered)
+	if err != nil {
+		d.log.Warn("cannot apply discovered jobs", zap.Error(err))
+		return
+	}
+	d.log.Info(
+		"discovered jobs are applied",
+		zap.Strings("added", changes.Added),
+		zap.Strings("removed", changes.Removed),
+		zap.Int("running", len(discovered)),
+	)
+}
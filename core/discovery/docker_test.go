@@ -0,0 +1,137 @@
+package discovery_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/discovery"
+	"github.com/fmotalleb/crontab-go/core/jobs"
+)
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fakeDocker serves running containers and streams pushed events.
+type fakeDocker struct {
+	*httptest.Server
+	containers []container.Summary
+	events     chan events.Message
+}
+
+func newFakeDocker(t *testing.T, containers ...container.Summary) *fakeDocker {
+	t.Helper()
+	fake := &fakeDocker{containers: containers, events: make(chan events.Message)}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeDocker) Host() string {
+	return "tcp://" + f.Listener.Addr().String()
+}
+
+func (f *fakeDocker) serve(w http.ResponseWriter, r *http.Request) {
+	switch apiVersionPrefix.ReplaceAllString(r.URL.Path, "") {
+	case "/_ping":
+		w.Header().Set("Api-Version", "1.47")
+		_, _ = w.Write([]byte("OK"))
+	case "/containers/json":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(f.containers)
+	case "/events":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event := <-f.events:
+				_ = json.NewEncoder(w).Encode(event)
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+// fakeApplier records names of the applied jobs.
+type fakeApplier struct {
+	mu      sync.Mutex
+	applied chan []string
+}
+
+func (a *fakeApplier) ApplySource(source string, applied []*config.JobConfig) (jobs.Changes, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	names := []string{}
+	for _, job := range applied {
+		names = append(names, job.Name)
+	}
+	if source == discovery.Source {
+		a.applied <- names
+	}
+	return jobs.Changes{}, nil
+}
+
+func (a *fakeApplier) next(t *testing.T) []string {
+	t.Helper()
+	select {
+	case names := <-a.applied:
+		return names
+	case <-time.After(5 * time.Second):
+		t.Fatal("discovered jobs were not applied")
+		return nil
+	}
+}
+
+func TestDocker_Discovery(t *testing.T) {
+	fake := newFakeDocker(t, container.Summary{
+		ID:    "db-id",
+		Names: []string{"/db"},
+		Labels: map[string]string{
+			"crontab-go.job.backup.cron":    "@daily",
+			"crontab-go.job.backup.command": "backup",
+		},
+	}, container.Summary{ID: "plain-id", Names: []string{"/plain"}})
+	applier := &fakeApplier{applied: make(chan []string)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go discovery.NewDocker(fake.Host(), "", applier, zap.NewNop()).Run(ctx)
+
+	assert.Equal(t, []string{"db.backup"}, applier.next(t))
+
+	fake.events <- events.Message{
+		Type:   events.ContainerEventType,
+		Action: events.ActionStart,
+		Actor: events.Actor{
+			ID: "web-id",
+			Attributes: map[string]string{
+				"name":                          "web",
+				"image":                         "nginx",
+				"crontab-go.job.reload.cron":    "@hourly",
+				"crontab-go.job.reload.command": "nginx -s reload",
+			},
+		},
+	}
+	assert.Equal(t, []string{"db.backup", "web.reload"}, applier.next(t))
+
+	fake.events <- events.Message{
+		Type:   events.ContainerEventType,
+		Action: events.ActionDie,
+		Actor:  events.Actor{ID: "db-id", Attributes: map[string]string{"name": "db"}},
+	}
+	assert.Equal(t, []string{"web.reload"}, applier.next(t))
+}
@@ -0,0 +1,164 @@
+// Package discovery discovers jobs declared outside of the config file (e.g. labels of containers)
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmotalleb/go-tools/defaulter"
+
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+// DefaultPrefix is the default prefix of labels declaring jobs, e.g. `crontab-go.job.backup.cron`.
+const DefaultPrefix = "crontab-go"
+
+// Container is a running container that may declare jobs using its labels.
+type Container struct {
+	ID     string
+	Name   string
+	Labels map[string]string
+}
+
+// JobsOf parses jobs declared by labels of the container in form of `<prefix>.job.<job name>.<key>`,
+// every job becomes a docker-attach task executed in the container.
+// Jobs are named `<container name>.<job name>` and sorted by name, jobs that cannot be parsed are reported in the error.
+func JobsOf(connection string, prefix string, c Container) ([]*config.JobConfig, error) {
+	declared := make(map[string]map[string]string)
+	jobPrefix := prefix + ".job."
+	for label, value := range c.Labels {
+		rest, ok := strings.CutPrefix(label, jobPrefix)
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		if dot <= 0 {
+			continue
+		}
+		name, key := rest[:dot], rest[dot+1:]
+		if declared[name] == nil {
+			declared[name] = make(map[string]string)
+		}
+		declared[name][key] = value
+	}
+
+	jobs := make([]*config.JobConfig, 0, len(declared))
+	var errs []error
+	for name, fields := range declared {
+		job, err := jobOf(connection, c, name, fields)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %q of container %s: %w", name, c.Name, err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b *config.JobConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return jobs, errors.Join(errs...)
+}
+
+func jobOf(connection string, c Container, name string, fields map[string]string) (*config.JobConfig, error) {
+	p := &parser{fields: fields}
+	task := config.Task{
+		Command:          p.string("command"),
+		UserName:         p.string("user"),
+		WorkingDirectory: p.string("working-dir"),
+		Timeout:          p.duration("timeout"),
+		Retries:          p.uint("retries"),
+		RetryDelay:       p.duration("retry-delay"),
+		Connections: []config.TaskConnection{
+			{
+				DockerConnection: connection,
+				ContainerName:    c.ID,
+				Tty:              p.bool("tty"),
+			},
+		},
+	}
+	job := &config.JobConfig{
+		Name:        fmt.Sprintf("%s.%s", c.Name, name),
+		Description: fmt.Sprintf("discovered from labels of container %s", c.Name),
+		Concurrency: uint(p.uint("concurrency")),
+		Overlap:     config.OverlapPolicy(p.string("overlap")),
+		Tasks:       []config.Task{task},
+	}
+	if cron := p.string("cron"); cron != "" {
+		job.Events = append(job.Events, config.JobEvent{Cron: cron})
+	}
+	if interval := p.duration("interval"); interval != 0 {
+		job.Events = append(job.Events, config.JobEvent{Interval: interval})
+	}
+	if p.bool("on-init") {
+		job.Events = append(job.Events, config.JobEvent{OnInit: true})
+	}
+	for key := range fields {
+		if !p.used[key] {
+			p.errs = append(p.errs, fmt.Errorf("unknown key %q", key))
+		}
+	}
+	switch {
+	case len(p.errs) != 0:
+		return nil, errors.Join(p.errs...)
+	case task.Command == "":
+		return nil, errors.New("command is required")
+	case len(job.Events) == 0:
+		return nil, errors.New("one of cron, interval or on-init is required")
+	}
+	defaulter.ApplyDefaults(job, job)
+	return job, nil
+}
+
+// parser reads values of the job from its labels, parse errors are collected.
+type parser struct {
+	fields map[string]string
+	used   map[string]bool
+	errs   []error
+}
+
+func (p *parser) string(key string) string {
+	if p.used == nil {
+		p.used = make(map[string]bool)
+	}
+	p.used[key] = true
+	return strings.TrimSpace(p.fields[key])
+}
+
+func (p *parser) duration(key string) time.Duration {
+	value := p.string(key)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s: %w", key, err))
+	}
+	return d
+}
+
+func (p *parser) uint(key string) uint64 {
+	value := p.string(key)
+	if value == "" {
+		return 0
+	}
+	u, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s: %w", key, err))
+	}
+	return u
+}
+
+func (p *parser) bool(key string) bool {
+	value := p.string(key)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s: %w", key, err))
+	}
+	return b
+}
@@ -0,0 +1,86 @@
+package discovery_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/discovery"
+)
+
+func TestJobsOf(t *testing.T) {
+	jobs, err := discovery.JobsOf("unix:///var/run/docker.sock", discovery.DefaultPrefix, discovery.Container{
+		ID:   "abc",
+		Name: "db",
+		Labels: map[string]string{
+			"crontab-go.job.backup.cron":        "0 3 * * *",
+			"crontab-go.job.backup.command":     "pg_dump app > /backups/app.sql",
+			"crontab-go.job.backup.user":        "postgres",
+			"crontab-go.job.backup.timeout":     "10m",
+			"crontab-go.job.backup.retries":     "2",
+			"crontab-go.job.backup.retry-delay": "30s",
+			"crontab-go.job.backup.overlap":     "queue",
+			"crontab-go.job.backup.tty":         "true",
+			"crontab-go.job.vacuum.interval":    "1h",
+			"crontab-go.job.vacuum.command":     "vacuumdb --all",
+			"crontab-go.job.vacuum.overlap":     "skip",
+			"com.docker.compose.service":        "db",
+			"other.job.ignored.command":         "true",
+			"crontab-go.job.malformed":          "true",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(jobs))
+
+	backup := jobs[0]
+	assert.Equal(t, "db.backup", backup.Name)
+	assert.Equal(t, []config.JobEvent{{Cron: "0 3 * * *"}}, backup.Events)
+	assert.Equal(t, config.OverlapQueue, backup.Overlap)
+	task := backup.Tasks[0]
+	assert.Equal(t, "pg_dump app > /backups/app.sql", task.Command)
+	assert.Equal(t, "postgres", task.UserName)
+	assert.Equal(t, 10*time.Minute, task.Timeout)
+	assert.Equal(t, uint64(2), task.Retries)
+	assert.Equal(t, 30*time.Second, task.RetryDelay)
+	assert.Equal(t, []config.TaskConnection{
+		{DockerConnection: "unix:///var/run/docker.sock", ContainerName: "abc", Tty: true},
+	}, task.Connections)
+
+	vacuum := jobs[1]
+	assert.Equal(t, "db.vacuum", vacuum.Name)
+	assert.Equal(t, []config.JobEvent{{Interval: time.Hour}}, vacuum.Events)
+	assert.Equal(t, config.OverlapSkip, vacuum.Overlap)
+}
+
+func TestJobsOf_Invalid(t *testing.T) {
+	tests := map[string]map[string]string{
+		"command is required": {
+			"app.job.backup.cron": "@daily",
+		},
+		"one of cron, interval or on-init is required": {
+			"app.job.backup.command": "backup",
+		},
+		"invalid timeout": {
+			"app.job.backup.command": "backup",
+			"app.job.backup.cron":    "@daily",
+			"app.job.backup.timeout": "soon",
+		},
+		"unknown key \"schedule\"": {
+			"app.job.backup.command":  "backup",
+			"app.job.backup.schedule": "@daily",
+		},
+	}
+	for message, labels := range tests {
+		labels["app.job.valid.command"] = "true"
+		labels["app.job.valid.on-init"] = "true"
+		jobs, err := discovery.JobsOf("", "app", discovery.Container{ID: "abc", Name: "web", Labels: labels})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), message)
+		assert.Contains(t, err.Error(), `job "backup" of container web`)
+		// valid jobs of the container are still discovered
+		assert.Equal(t, 1, len(jobs))
+		assert.Equal(t, "web.valid", jobs[0].Name)
+	}
+}
@@ -2,7 +2,9 @@
 package event
 
 import (
+	"cmp"
 	"context"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
@@ -11,11 +13,17 @@ import (
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/history"
 )
 
 const (
 	CronEventsMetricName = "cron"
 	CronEventsMetricHelp = "amount of events dispatched using cron"
+
+	// DefaultCatchUpLookback limits how far back missed ticks are replayed if no lookback is configured.
+	DefaultCatchUpLookback = 24 * time.Hour
+	// MaxCatchUpTicks limits the amount of replayed ticks, only the latest ones are replayed.
+	MaxCatchUpTicks = 100
 )
 
 func init() {
@@ -24,7 +32,11 @@ func init() {
 
 func newCronGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction.EventGenerator, bool) {
 	if cfg.Cron != "" {
-		return NewCron(cfg.Cron, global.Get[*cron.Cron](), log), true
+		c := NewCron(cfg.Cron, global.Get[*cron.Cron](), log)
+		if cfg.CatchUp != nil {
+			c.EnableCatchUp(cfg.CatchUp, global.Get[*history.Store]())
+		}
+		return c, true
 	}
 	return nil, false
 }
@@ -34,9 +46,11 @@ type Cron struct {
 	logger       *zap.Logger
 	cron         *cron.Cron
 	entry        *cron.EntryID
+	catchUp      *config.CatchUp
+	store        *history.Store
 }
 
-func NewCron(schedule string, c *cron.Cron, logger *zap.Logger) abstraction.EventGenerator {
+func NewCron(schedule string, c *cron.Cron, logger *zap.Logger) *Cron {
 	global.RegisterCounter(
 		CronEventsMetricName,
 		CronEventsMetricHelp,
@@ -54,8 +68,19 @@ func NewCron(schedule string, c *cron.Cron, logger *zap.Logger) abstraction.Even
 	return cron
 }
 
+// EnableCatchUp persists ticks of the schedule into the store, so ticks missed while the application was down
+// are emitted (with `missed: true`) once the ticker channel is built.
+func (c *Cron) EnableCatchUp(catchUp *config.CatchUp, store *history.Store) {
+	if store == nil {
+		c.logger.Warn("catch-up is enabled but no state directory is set, missed ticks are not tracked")
+		return
+	}
+	c.catchUp = catchUp
+	c.store = store
+}
+
 // BuildTickChannel implements abstraction.Scheduler.
-func (c *Cron) BuildTickChannel(ed abstraction.EventDispatcher) {
+func (c *Cron) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
 	if c.entry != nil {
 		c.logger.Fatal("already built the ticker channel")
 	}
@@ -64,18 +89,20 @@ func (c *Cron) BuildTickChannel(ed abstraction.EventDispatcher) {
 	if err != nil {
 		c.logger.Warn("cannot initialize cron", zap.Error(err))
 	} else {
+		c.emitMissed(ctx, ed, schedule, time.Now())
 		entry := c.cron.Schedule(
 			schedule,
 			&cronJob{
 				logger:    c.logger,
 				scheduler: c.cronSchedule,
 				notify:    notifyChan,
+				record:    c.recordTick,
+				done:      ctx.Done(),
 			},
 		)
 		c.entry = &entry
+		defer c.cron.Remove(entry)
 	}
-	ctx, cancel := context.WithCancel(global.CTX().Context)
-	defer cancel()
 	for {
 		select {
 		case e := <-notifyChan:
@@ -91,18 +118,106 @@ func (c *Cron) BuildTickChannel(ed abstraction.EventDispatcher) {
 	}
 }
 
+// emitMissed emits ticks of the schedule missed since the last recorded tick according to the catch-up policy.
+func (c *Cron) emitMissed(ctx context.Context, ed abstraction.EventDispatcher, schedule cron.Schedule, now time.Time) {
+	if c.catchUp == nil {
+		return
+	}
+	defer c.recordTick(now)
+	last, ok, err := c.store.LastTick(c.catchUp.Job, c.cronSchedule)
+	if err != nil {
+		c.logger.Error("failed to read the last tick, skipping catch-up", zap.Error(err))
+		return
+	}
+	if !ok {
+		c.logger.Debug("no tick recorded yet, nothing to catch up")
+		return
+	}
+	missed, dropped := missedTicks(schedule, last, now, cmp.Or(c.catchUp.Lookback, DefaultCatchUpLookback))
+	if len(missed) == 0 {
+		return
+	}
+	if dropped != 0 && c.catchUp.Policy != config.CatchUpOnce {
+		c.logger.Warn("too many missed ticks, only the latest ones are replayed", zap.Int("dropped", dropped), zap.Int("limit", MaxCatchUpTicks))
+	}
+	if c.catchUp.Policy == config.CatchUpOnce {
+		missed = missed[len(missed)-1:]
+	}
+	c.logger.Info(
+		"catching up missed ticks",
+		zap.Time("last-tick", last),
+		zap.Int("count", len(missed)),
+		zap.String("policy", string(c.catchUp.Policy)),
+	)
+	for _, tick := range missed {
+		ed.Emit(ctx, NewMetaData(
+			"cron",
+			map[string]any{
+				"schedule":  c.cronSchedule,
+				"missed":    true,
+				"scheduled": tick.Format(time.RFC3339),
+			},
+		))
+		global.IncMetric(
+			CronEventsMetricName,
+			CronEventsMetricHelp,
+			prometheus.Labels{"cron": c.cronSchedule},
+		)
+	}
+}
+
+func (c *Cron) recordTick(at time.Time) {
+	if c.catchUp == nil {
+		return
+	}
+	if err := c.store.RecordTick(c.catchUp.Job, c.cronSchedule, at); err != nil {
+		c.logger.Warn("failed to record the tick", zap.Error(err))
+	}
+}
+
+// missedTicks lists ticks of the schedule after last and up to now, limited to the lookback window
+// and to the latest MaxCatchUpTicks ticks. It returns the amount of dropped older ticks as well.
+func missedTicks(schedule cron.Schedule, last time.Time, now time.Time, lookback time.Duration) ([]time.Time, int) {
+	from := last
+	if limit := now.Add(-lookback); from.Before(limit) {
+		from = limit
+	}
+	ticks := []time.Time{}
+	dropped := 0
+	for tick := schedule.Next(from); !tick.IsZero() && !tick.After(now); tick = schedule.Next(tick) {
+		ticks = append(ticks, tick)
+		if len(ticks) > 2*MaxCatchUpTicks {
+			dropped += len(ticks) - MaxCatchUpTicks
+			ticks = append([]time.Time{}, ticks[len(ticks)-MaxCatchUpTicks:]...)
+		}
+	}
+	if len(ticks) > MaxCatchUpTicks {
+		dropped += len(ticks) - MaxCatchUpTicks
+		ticks = ticks[len(ticks)-MaxCatchUpTicks:]
+	}
+	return ticks, dropped
+}
+
 type cronJob struct {
 	logger    *zap.Logger
 	scheduler string
 	notify    chan<- abstraction.Event
+	record    func(time.Time)
+	done      <-chan struct{}
 }
 
 func (j *cronJob) Run() {
 	j.logger.Debug("cron tick received")
-	j.notify <- NewMetaData(
+	j.record(time.Now())
+	event := NewMetaData(
 		"cron",
 		map[string]any{
 			"schedule": j.scheduler,
+			"missed":   false,
 		},
 	)
+	select {
+	case j.notify <- event:
+	case <-j.done:
+	}
 }
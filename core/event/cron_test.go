@@ -0,0 +1,89 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/history"
+)
+
+func TestMissedTicks(t *testing.T) {
+	schedule, err := config.DefaultCronParser.Parse("0 0 3 * * *")
+	assert.NoError(t, err)
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	ticks, dropped := missedTicks(schedule, now.Add(-50*time.Hour), now, 24*7*time.Hour)
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 4, 3, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC),
+	}, ticks)
+
+	// limited to the lookback window
+	ticks, _ = missedTicks(schedule, now.Add(-50*time.Hour), now, 10*time.Hour)
+	assert.Equal(t, []time.Time{time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)}, ticks)
+
+	ticks, _ = missedTicks(schedule, now.Add(-time.Hour), now, time.Hour)
+	assert.Equal(t, []time.Time{}, ticks)
+}
+
+func TestMissedTicks_Limit(t *testing.T) {
+	schedule, err := config.DefaultCronParser.Parse("* * * * * *")
+	assert.NoError(t, err)
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	ticks, dropped := missedTicks(schedule, now.Add(-48*time.Hour), now, DefaultCatchUpLookback)
+	assert.Equal(t, MaxCatchUpTicks, len(ticks))
+	assert.Equal(t, int(DefaultCatchUpLookback/time.Second)-MaxCatchUpTicks, dropped)
+	assert.Equal(t, now, ticks[len(ticks)-1])
+	assert.Equal(t, now.Add(-(MaxCatchUpTicks-1)*time.Second), ticks[0])
+}
+
+func catchUpEvents(t *testing.T, store *history.Store, policy config.CatchUpPolicy, now time.Time) []map[string]any {
+	t.Helper()
+	schedule, err := config.DefaultCronParser.Parse("0 0 3 * * *")
+	assert.NoError(t, err)
+	c := NewCron("0 0 3 * * *", nil, zap.NewNop())
+	c.EnableCatchUp(&config.CatchUp{Job: "backup", Policy: policy, Lookback: 7 * 24 * time.Hour}, store)
+
+	received := []map[string]any{}
+	ed := signals.NewSync[abstraction.Event]()
+	ed.AddListener(func(_ context.Context, e abstraction.Event) {
+		received = append(received, e.GetData())
+	})
+	c.emitMissed(t.Context(), ed, schedule, now)
+	return received
+}
+
+func TestCron_CatchUp(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	// first boot only records the tick
+	assert.Equal(t, 0, len(catchUpEvents(t, store, config.CatchUpAll, now)))
+
+	// down for three days
+	now = now.Add(72 * time.Hour)
+	received := catchUpEvents(t, store, config.CatchUpAll, now)
+	assert.Equal(t, 3, len(received))
+	assert.Equal(t, true, received[0]["missed"])
+	assert.Equal(t, "2024-01-06T03:00:00Z", received[0]["scheduled"])
+	assert.Equal(t, "2024-01-08T03:00:00Z", received[2]["scheduled"])
+
+	// caught up ticks are not emitted again
+	assert.Equal(t, 0, len(catchUpEvents(t, store, config.CatchUpAll, now)))
+
+	now = now.Add(48 * time.Hour)
+	received = catchUpEvents(t, store, config.CatchUpOnce, now)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "2024-01-10T03:00:00Z", received[0]["scheduled"])
+}
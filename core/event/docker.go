@@ -3,11 +3,13 @@ package event
 import (
 	"cmp"
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -42,6 +44,8 @@ func newDockerGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction.Even
 			d.Image,
 			d.Actions,
 			d.Labels,
+			d.Type,
+			d.Since,
 			cmp.Or(d.ErrorLimit, 1),
 			cmp.Or(d.ErrorLimitPolicy, config.ErrorPolReconnect),
 			cmp.Or(d.ErrorThrottle, time.Second*5),
@@ -54,15 +58,32 @@ func newDockerGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction.Even
 
 type DockerEvent struct {
 	connection       string
+	eventType        events.Type
 	containerMatcher regexp.Regexp
 	imageMatcher     regexp.Regexp
 	actions          *utils.List[events.Action]
 	labels           map[string]regexp.Regexp
+	filters          filters.Args
+	since            time.Duration
 	errorThreshold   uint
 	errorPolicy      config.ErrorLimitPolicy
 	errorThrottle    time.Duration
 	log              *zap.Logger
 	metricLabels     prometheus.Labels
+
+	// lastEvent is the time (unix nano) of the last received event, reconnections resume from it
+	// or from resumeFrom (the first connection) if no event is received yet
+	lastEvent  int64
+	resumeFrom int64
+	// boundary holds events received at lastEvent, they are replayed after reconnections
+	boundary map[eventKey]struct{}
+}
+
+// eventKey identifies events emitted at the same nanosecond.
+type eventKey struct {
+	eventType events.Type
+	actor     string
+	action    events.Action
 }
 
 func NewDockerEvent(
@@ -71,6 +92,8 @@ func NewDockerEvent(
 	imageMatcher string,
 	actions []string,
 	labels map[string]string,
+	eventType string,
+	since time.Duration,
 	errorLimit uint,
 	errorPolicy config.ErrorLimitPolicy,
 	errorThrottle time.Duration,
@@ -81,6 +104,7 @@ func NewDockerEvent(
 		"containerMatcher": containerMatcher,
 		"imageMatcher":     imageMatcher,
 		"actions":          strings.Join(actions, "||"),
+		"type":             eventType,
 	}
 	global.RegisterCounter(
 		DockerEventsMetricName,
@@ -89,10 +113,13 @@ func NewDockerEvent(
 	)
 	return &DockerEvent{
 		connection:       connection,
+		eventType:        events.Type(eventType),
 		containerMatcher: *regexp.MustCompile(containerMatcher),
 		imageMatcher:     *regexp.MustCompile(imageMatcher),
 		actions:          toAction(actions),
 		labels:           reshapeLabelMatcher(labels),
+		filters:          buildFilters(events.Type(eventType), containerMatcher, imageMatcher, actions, labels),
+		since:            since,
 		errorThreshold:   errorLimit,
 		errorPolicy:      errorPolicy,
 		errorThrottle:    errorThrottle,
@@ -101,15 +128,15 @@ func NewDockerEvent(
 	}
 }
 
-func (dockerEvent *DockerEvent) BuildTickChannel(ed abstraction.EventDispatcher) {
-	for {
-		if !dockerEvent.connectAndListen(ed) {
+func (dockerEvent *DockerEvent) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
+	for ctx.Err() == nil {
+		if !dockerEvent.connectAndListen(ctx, ed) {
 			return // stop if policy says to give up
 		}
 	}
 }
 
-func (dockerEvent *DockerEvent) connectAndListen(ed abstraction.EventDispatcher) bool {
+func (dockerEvent *DockerEvent) connectAndListen(c context.Context, ed abstraction.EventDispatcher) bool {
 	cli, err := client.NewClientWithOpts(
 		client.WithHost(dockerEvent.connection),
 		client.WithAPIVersionNegotiation(),
@@ -120,10 +147,10 @@ func (dockerEvent *DockerEvent) connectAndListen(ed abstraction.EventDispatcher)
 	}
 	defer cli.Close()
 
-	ctx, cancel := context.WithCancel(global.CTX())
+	ctx, cancel := context.WithCancel(c)
 	defer cancel()
 
-	msg, errs := cli.Events(ctx, events.ListOptions{})
+	msg, errs := cli.Events(ctx, dockerEvent.listOptions())
 	errCount := concurrency.NewLockedValue(uint(0))
 
 	for {
@@ -163,8 +190,13 @@ func (dockerEvent *DockerEvent) connectAndListen(ed abstraction.EventDispatcher)
 
 		case event := <-msg:
 			dockerEvent.log.Debug("received an event from docker", zap.Any("event", event))
+			// events replayed after a reconnection may have been received before
+			if dockerEvent.received(&event) {
+				continue
+			}
 			if dockerEvent.matches(&event) {
 				meta := NewMetaData("docker", map[string]any{
+					"type":       event.Type,
 					"scope":      event.Scope,
 					"action":     event.Action,
 					"actor":      event.Actor.ID,
@@ -184,15 +216,107 @@ func (dockerEvent *DockerEvent) connectAndListen(ed abstraction.EventDispatcher)
 	}
 }
 
+// received reports whether the event was received before, replays start at the timestamp of the last received event
+// so only events of that timestamp are compared (distinct events may share a nanosecond).
+func (dockerEvent *DockerEvent) received(event *events.Message) bool {
+	if event.TimeNano == 0 {
+		return false
+	}
+	key := eventKey{eventType: event.Type, actor: event.Actor.ID, action: event.Action}
+	switch {
+	case event.TimeNano < dockerEvent.lastEvent:
+		return true
+	case event.TimeNano == dockerEvent.lastEvent:
+		if _, ok := dockerEvent.boundary[key]; ok {
+			return true
+		}
+	default:
+		dockerEvent.lastEvent = event.TimeNano
+		dockerEvent.boundary = make(map[eventKey]struct{})
+	}
+	dockerEvent.boundary[key] = struct{}{}
+	return false
+}
+
+// listOptions pushes filters down to docker, the first connection replays events of the since window (if set)
+// and reconnections resume from the last received event (or the first connection) so events emitted while reconnecting are not lost.
+func (dockerEvent *DockerEvent) listOptions() events.ListOptions {
+	opts := events.ListOptions{Filters: dockerEvent.filters}
+	switch {
+	case dockerEvent.lastEvent != 0:
+		opts.Since = formatTimestamp(dockerEvent.lastEvent)
+	case dockerEvent.resumeFrom != 0:
+		opts.Since = formatTimestamp(dockerEvent.resumeFrom)
+	default:
+		dockerEvent.resumeFrom = time.Now().Add(-dockerEvent.since).UnixNano()
+		if dockerEvent.since > 0 {
+			opts.Since = formatTimestamp(dockerEvent.resumeFrom)
+		}
+	}
+	return opts
+}
+
+// formatTimestamp formats unix nano timestamps the way docker accepts them (`seconds.nanoseconds`).
+func formatTimestamp(nano int64) string {
+	return fmt.Sprintf("%d.%09d", nano/int64(time.Second), nano%int64(time.Second))
+}
+
+// buildFilters translates matchers into filters of docker, so only relevant events are sent over the wire.
+// Regex matchers can only be pushed down if they match an exact value (e.g. `^web$`), events are matched again once received.
+func buildFilters(eventType events.Type, name string, image string, actions []string, labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	if eventType != "" {
+		args.Add("type", string(eventType))
+		// objects are filtered by their name (or id) using a filter named after their type
+		if exact, ok := exactMatch(name); ok {
+			args.Add(string(eventType), exact)
+		}
+	}
+	if exact, ok := exactMatch(image); ok {
+		args.Add("image", exact)
+	}
+	for _, action := range actions {
+		args.Add("event", action)
+	}
+	for key, value := range labels {
+		if exact, ok := exactMatch(value); ok {
+			args.Add("label", key+"="+exact)
+		} else {
+			args.Add("label", key)
+		}
+	}
+	return args
+}
+
+// exactMatch returns the value that the pattern matches if it only matches a single value (e.g. `^web$`).
+func exactMatch(pattern string) (string, bool) {
+	inner, ok := strings.CutPrefix(pattern, "^")
+	if !ok {
+		return "", false
+	}
+	inner, ok = strings.CutSuffix(inner, "$")
+	if !ok || inner == "" {
+		return "", false
+	}
+	re, err := regexp.Compile(inner)
+	if err != nil {
+		return "", false
+	}
+	return re.LiteralPrefix()
+}
+
 func (dockerEvent *DockerEvent) matches(msg *events.Message) bool {
+	if dockerEvent.eventType != "" && msg.Type != dockerEvent.eventType {
+		return false
+	}
 	if dockerEvent.actions.IsNotEmpty() && !dockerEvent.actions.Contains(msg.Action) {
 		return false
 	}
-	if !dockerEvent.containerMatcher.MatchString(msg.Actor.Attributes["name"]) {
+	if !dockerEvent.containerMatcher.MatchString(nameOf(msg)) {
 		return false
 	}
 
-	if !dockerEvent.imageMatcher.MatchString(msg.Actor.Attributes["image"]) {
+	if !dockerEvent.imageMatcher.MatchString(imageOf(msg)) {
 		return false
 	}
 
@@ -204,6 +328,19 @@ func (dockerEvent *DockerEvent) matches(msg *events.Message) bool {
 	return true
 }
 
+// nameOf returns the name of the object emitting the event, objects without a name attribute (e.g. volumes) are named by their id.
+func nameOf(msg *events.Message) string {
+	return cmp.Or(msg.Actor.Attributes["name"], msg.Actor.ID)
+}
+
+// imageOf returns the image of the event, which is the object itself for image events.
+func imageOf(msg *events.Message) string {
+	if msg.Type == events.ImageEventType {
+		return nameOf(msg)
+	}
+	return msg.Actor.Attributes["image"]
+}
+
 func (dockerEvent *DockerEvent) shouldReconnect() bool {
 	switch dockerEvent.errorPolicy {
 	case config.ErrorPolReconnect:
@@ -0,0 +1,200 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+func TestDockerEvent_Filters(t *testing.T) {
+	args := buildFilters(
+		events.VolumeEventType,
+		"^backups$",
+		"nginx",
+		[]string{"prune", "destroy"},
+		map[string]string{"env": "^prod$", "team": "ops|dev"},
+	)
+	assert.Equal(t, []string{"volume"}, args.Get("type"))
+	assert.Equal(t, []string{"backups"}, args.Get("volume"))
+	// regex matchers are matched once the event is received
+	assert.Equal(t, []string{}, args.Get("image"))
+	assert.True(t, args.ExactMatch("event", "prune"))
+	assert.True(t, args.ExactMatch("event", "destroy"))
+	assert.True(t, args.ExactMatch("label", "env=prod"))
+	assert.True(t, args.ExactMatch("label", "team"))
+
+	args = buildFilters("", "^web$", `^nginx:1\.27$`, nil, nil)
+	assert.Equal(t, 0, len(args.Get("type")))
+	assert.Equal(t, 0, len(args.Get("container")))
+	assert.Equal(t, []string{"nginx:1.27"}, args.Get("image"))
+
+	// `.` matches any character
+	args = buildFilters("", "", "^nginx:1.27$", nil, nil)
+	assert.Equal(t, 0, len(args.Get("image")))
+}
+
+func TestDockerEvent_LabelFilters(t *testing.T) {
+	label := func(labels map[string]string) []string {
+		values := buildFilters("", "", "", nil, labels).Get("label")
+		slices.Sort(values)
+		return values
+	}
+	// exact values are filtered by the daemon
+	assert.Equal(t, []string{"env=prod"}, label(map[string]string{"env": "^prod$"}))
+	// regex values only filter by the key, the value is matched once the event is received
+	assert.Equal(t, []string{"env"}, label(map[string]string{"env": "prod|staging"}))
+	assert.Equal(t, []string{"env"}, label(map[string]string{"env": "^prod.*$"}))
+	// exact alternatives combined by a regex are not a single value
+	assert.Equal(t, []string{"env"}, label(map[string]string{"env": "^prod$|^staging$"}))
+	assert.Equal(
+		t,
+		[]string{"env=prod", "team"},
+		label(map[string]string{"env": "^prod$", "team": "ops|dev"}),
+	)
+}
+
+func TestDockerEvent_MatchesTypes(t *testing.T) {
+	newEvent := func(eventType string, name string, image string) *DockerEvent {
+		return NewDockerEvent("", name, image, nil, nil, eventType, 0, 1, config.ErrorPolGiveUp, 0, zap.NewNop()).(*DockerEvent)
+	}
+	volumePrune := &events.Message{Type: events.VolumeEventType, Action: events.ActionPrune, Actor: events.Actor{ID: "backups"}}
+	disconnect := &events.Message{
+		Type:   events.NetworkEventType,
+		Action: events.ActionDisconnect,
+		Actor:  events.Actor{ID: "n1", Attributes: map[string]string{"name": "backend", "container": "c1"}},
+	}
+	pull := &events.Message{Type: events.ImageEventType, Action: events.ActionPull, Actor: events.Actor{ID: "nginx:1.27", Attributes: map[string]string{"name": "nginx:1.27"}}}
+
+	assert.True(t, newEvent("volume", "^backups$", "").matches(volumePrune))
+	assert.False(t, newEvent("network", "", "").matches(volumePrune))
+	assert.True(t, newEvent("network", "back", "").matches(disconnect))
+	assert.True(t, newEvent("", "", "").matches(disconnect))
+	assert.True(t, newEvent("image", "", "^nginx").matches(pull))
+	assert.False(t, newEvent("image", "", "^redis").matches(pull))
+}
+
+// fakeEvents serves the event stream of docker, every connection receives the next batch of events and is closed afterwards.
+type fakeEvents struct {
+	*httptest.Server
+	mu       sync.Mutex
+	batches  [][]events.Message
+	requests []*http.Request
+}
+
+func (f *fakeEvents) serve(w http.ResponseWriter, r *http.Request) {
+	path := regexp.MustCompile(`^/v[0-9.]+`).ReplaceAllString(r.URL.Path, "")
+	switch path {
+	case "/_ping":
+		w.Header().Set("Api-Version", "1.47")
+		_, _ = w.Write([]byte("OK"))
+	case "/events":
+		f.mu.Lock()
+		f.requests = append(f.requests, r)
+		var batch []events.Message
+		if len(f.batches) > 0 {
+			batch, f.batches = f.batches[0], f.batches[1:]
+		}
+		last := len(f.batches) == 0
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		for _, event := range batch {
+			_ = json.NewEncoder(w).Encode(event)
+		}
+		w.(http.Flusher).Flush()
+		if last {
+			<-r.Context().Done()
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (f *fakeEvents) request(i int) *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i >= len(f.requests) {
+		return nil
+	}
+	return f.requests[i]
+}
+
+func TestDockerEvent_ResumesAfterReconnect(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	event := func(action events.Action, at time.Time) events.Message {
+		return events.Message{
+			Type:     events.VolumeEventType,
+			Action:   action,
+			Actor:    events.Actor{ID: "backups"},
+			Time:     at.Unix(),
+			TimeNano: at.UnixNano(),
+		}
+	}
+	fake := &fakeEvents{batches: [][]events.Message{
+		{event(events.ActionCreate, start)},
+		// the create event is replayed as it happened at the resume timestamp,
+		// the destroy event is new although it shares the timestamp
+		{event(events.ActionCreate, start), event(events.ActionDestroy, start), event(events.ActionPrune, start.Add(time.Second))},
+	}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	defer fake.Close()
+
+	received := make(chan map[string]any, 8)
+	ed := signals.NewSync[abstraction.Event]()
+	ed.AddListener(func(_ context.Context, e abstraction.Event) {
+		received <- e.GetData()
+	})
+	gen := NewDockerEvent(
+		"tcp://"+fake.Listener.Addr().String(), "", "", nil, nil, "volume", time.Hour,
+		1, config.ErrorPolReconnect, time.Millisecond, zap.NewNop(),
+	)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gen.BuildTickChannel(ctx, ed)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, action := range []events.Action{events.ActionCreate, events.ActionDestroy, events.ActionPrune} {
+		select {
+		case data := <-received:
+			assert.Equal[any](t, action, data["action"])
+			assert.Equal[any](t, events.VolumeEventType, data["type"])
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s event", action)
+		}
+	}
+	select {
+	case data := <-received:
+		t.Fatalf("unexpected event: %v", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first, second := fake.request(0), fake.request(1)
+	// the first connection replays the since window
+	since := first.URL.Query().Get("since")
+	assert.NotEqual(t, "", since)
+	assert.True(t, since < formatTimestamp(start.UnixNano()))
+	assert.Equal(t, formatTimestamp(start.UnixNano()), second.URL.Query().Get("since"))
+	args, err := filters.FromJSON(second.URL.Query().Get("filters"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"volume"}, args.Get("type"))
+}
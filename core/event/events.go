@@ -8,6 +8,7 @@ import (
 
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/when"
 	"github.com/fmotalleb/crontab-go/generator"
 )
 
@@ -15,13 +16,29 @@ var eg = generator.New[*config.JobEvent, abstraction.EventGenerator]()
 
 func Build(log *zap.Logger, cfg *config.JobEvent) abstraction.EventGenerator {
 	if g, ok := eg.Get(log, cfg); ok {
-		return g
+		return withCondition(log, cfg, g)
 	}
 	err := fmt.Errorf("no event generator matched %+v", *cfg)
 	log.Warn("event.Build: generator not found", zap.Error(err))
 	return nil
 }
 
+// withCondition wraps the generator so only events satisfying the `when` condition of the config are emitted.
+func withCondition(log *zap.Logger, cfg *config.JobEvent, g abstraction.EventGenerator) abstraction.EventGenerator {
+	cond, err := when.Compile(cfg.When)
+	if err != nil {
+		log.Panic("invalid when condition", zap.Error(err))
+	}
+	if cond == nil {
+		return g
+	}
+	return &conditional{
+		EventGenerator: g,
+		cond:           cond,
+		log:            log.With(zap.Stringer("when", cond)),
+	}
+}
+
 type MetaData struct {
 	Emitter string
 	Extra   map[string]any
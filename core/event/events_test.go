@@ -1,13 +1,16 @@
 package event_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/maniartech/signals"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
+	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/event"
 	"github.com/fmotalleb/crontab-go/core/global"
@@ -136,3 +139,19 @@ func TestGetData(t *testing.T) {
 		})
 	}
 }
+
+func TestBuild_When(t *testing.T) {
+	for expr, expected := range map[string]int{
+		`eq .emitter "init"`: 1,
+		`eq .emitter "cron"`: 0,
+	} {
+		sch := event.Build(zap.NewNop(), &config.JobEvent{OnInit: true, When: expr})
+		received := 0
+		ed := signals.NewSync[abstraction.Event]()
+		ed.AddListener(func(context.Context, abstraction.Event) {
+			received++
+		})
+		sch.BuildTickChannel(t.Context(), ed)
+		assert.Equal(t, expected, received, expr)
+	}
+}
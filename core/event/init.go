@@ -36,8 +36,7 @@ func newInitGenerator(_ *zap.Logger, cfg *config.JobEvent) (abstraction.EventGen
 type Init struct{}
 
 // BuildTickChannel implements abstraction.Scheduler.
-func (c *Init) BuildTickChannel(ed abstraction.EventDispatcher) {
-	ctx := context.Background()
+func (c *Init) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
 	ed.Emit(ctx, NewMetaData("init", map[string]any{}))
 	global.IncMetric(
 		InitEventsMetricName,
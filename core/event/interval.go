@@ -52,15 +52,14 @@ func NewInterval(schedule time.Duration, logger *zap.Logger) abstraction.EventGe
 }
 
 // BuildTickChannel implements abstraction.Scheduler.
-func (c *Interval) BuildTickChannel(ed abstraction.EventDispatcher) {
+func (c *Interval) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
 	if c.ticker != nil {
 		c.logger.Fatal("already built the ticker channel")
 	}
 
 	c.ticker = time.NewTicker(c.duration)
-	ctx, cancel := context.WithCancel(global.CTX())
+	defer c.ticker.Stop()
 	intervalStr := c.duration.String()
-	defer cancel()
 	for {
 		select {
 		case i := <-c.ticker.C:
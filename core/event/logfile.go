@@ -2,14 +2,17 @@ package event
 
 import (
 	"bufio"
+	"bytes"
 	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +23,7 @@ import (
 	"github.com/fmotalleb/crontab-go/abstraction"
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/logfields"
 )
 
 const (
@@ -27,7 +31,14 @@ const (
 	LogEventsMetricHelp = "amount of events dispatched using log-file"
 )
 
-// TODO[epic=events] add watch method (probably after fs watcher is implemented)
+// logRotationGrace is how long a file that was rotated away (and not recreated) is drained before it is closed.
+const logRotationGrace = 5 * time.Second
+
+// logReadChunkSize is the amount of data read (and split into records) at once.
+const logReadChunkSize = 64 << 10
+
+// MaxLogRecordSize limits the length of records, longer lines are skipped and multiline records stop growing.
+const MaxLogRecordSize = 1 << 20
 
 func init() {
 	eg.Register(newLogListenerGenerator)
@@ -42,6 +53,9 @@ func newLogListenerGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction
 		cfg.LogFile,
 		cfg.LogLineBreaker,
 		cfg.LogMatcher,
+		cfg.LogMultilineStart,
+		cfg.LogFormat,
+		cfg.LogConditions,
 		cfg.LogCheckCycle,
 		log,
 	)
@@ -54,16 +68,28 @@ func newLogListenerGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction
 
 // LogFile represents a log file that triggers an event when its content changes.
 type LogFile struct {
-	logger       *zap.Logger
-	filePath     string
-	lineBreaker  string
-	matcher      *regexp.Regexp
-	checkCycle   time.Duration
-	metricLabels prometheus.Labels
+	logger         *zap.Logger
+	filePath       string
+	lineBreaker    string
+	matcher        *regexp.Regexp
+	multilineStart *regexp.Regexp
+	format         string
+	conditions     logfields.Conditions
+	checkCycle     time.Duration
+	rotationGrace  time.Duration
+	metricLabels   prometheus.Labels
 }
 
-func NewLogFile(filePath, lineBreaker, matcherStr string, checkCycle time.Duration, logger *zap.Logger) (*LogFile, error) {
-	lineBreaker = cmp.Or(lineBreaker, "\n")
+// NewLogFile creates a listener of the file (or glob), records are split using lineBreaker (escape sequences like `\r\n` are allowed).
+// If multilineStart is given, lines not matching it are appended to the previous record (e.g. stack traces),
+// if format (json or logfmt) is given records are parsed and conditions are applied on their fields.
+func NewLogFile(
+	filePath, lineBreaker, matcherStr, multilineStart, format string,
+	conditions []string,
+	checkCycle time.Duration,
+	logger *zap.Logger,
+) (*LogFile, error) {
+	lineBreaker = unescapeLineBreaker(cmp.Or(lineBreaker, "\n"))
 	matcherStr = cmp.Or(matcherStr, ".")
 	checkCycle = cmp.Or(checkCycle, time.Second)
 
@@ -71,6 +97,19 @@ func NewLogFile(filePath, lineBreaker, matcherStr string, checkCycle time.Durati
 	if err != nil {
 		return nil, fmt.Errorf("invalid log matcher: %w", err)
 	}
+	var multiline *regexp.Regexp
+	if multilineStart != "" {
+		if multiline, err = regexp.Compile(multilineStart); err != nil {
+			return nil, fmt.Errorf("invalid log multiline start: %w", err)
+		}
+	}
+	if format != "" && !slices.Contains(logfields.Formats, format) {
+		return nil, fmt.Errorf("unknown log format `%s`", format)
+	}
+	compiled, err := logfields.Compile(conditions)
+	if err != nil {
+		return nil, err
+	}
 	metricLabels := prometheus.Labels{
 		"file":         filePath,
 		"line_breaker": lineBreaker,
@@ -90,34 +129,39 @@ func NewLogFile(filePath, lineBreaker, matcherStr string, checkCycle time.Durati
 			zap.String("matcher", matcherStr),
 			zap.Duration("check_cycle", checkCycle),
 		),
-		filePath:     filePath,
-		lineBreaker:  lineBreaker,
-		matcher:      matcher,
-		checkCycle:   checkCycle,
-		metricLabels: metricLabels,
+		filePath:       filePath,
+		lineBreaker:    lineBreaker,
+		matcher:        matcher,
+		multilineStart: multiline,
+		format:         format,
+		conditions:     compiled,
+		checkCycle:     checkCycle,
+		rotationGrace:  logRotationGrace,
+		metricLabels:   metricLabels,
 	}, nil
 }
 
-func (lf *LogFile) BuildTickChannel(ed abstraction.EventDispatcher) {
-	ctx, cancel := context.WithCancel(global.CTX())
-	defer cancel()
-
-	file, err := os.Open(lf.filePath)
-	if err != nil {
-		lf.logger.Error("failed to open log file", zap.String("path", lf.filePath), zap.Error(err))
-		return
+// unescapeLineBreaker allows breakers given as escape sequences (e.g. `\r\n` in single quoted yaml).
+func unescapeLineBreaker(lineBreaker string) string {
+	if !strings.Contains(lineBreaker, `\`) {
+		return lineBreaker
 	}
+	if unquoted, err := strconv.Unquote(`"` + lineBreaker + `"`); err == nil && unquoted != "" {
+		return unquoted
+	}
+	return lineBreaker
+}
+
+// BuildTickChannel follows the file (or every file matching the glob) until ctx is canceled.
+// Content existing at startup is skipped, files appearing later are read from their beginning.
+func (lf *LogFile) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
+	files := make(map[string]*tailedFile)
 	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			lf.logger.Warn("failed to close log file", zap.Error(cerr))
+		for _, t := range files {
+			lf.close(t)
 		}
 	}()
-
-	reader := bufio.NewReader(file)
-	// Skip existing content
-	if _, err := reader.Discard(math.MaxInt64); err != nil && !errors.Is(err, io.EOF) {
-		lf.logger.Warn("failed to skip initial data", zap.Error(err))
-	}
+	lf.discover(ctx, ed, files, true)
 
 	ticker := time.NewTicker(lf.checkCycle)
 	defer ticker.Stop()
@@ -127,40 +171,303 @@ func (lf *LogFile) BuildTickChannel(ed abstraction.EventDispatcher) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			lf.processNewLines(ctx, reader, ed)
+			lf.discover(ctx, ed, files, false)
+			for _, t := range files {
+				lf.follow(ctx, ed, t)
+			}
 		}
 	}
 }
 
-func (lf *LogFile) processNewLines(ctx context.Context, reader *bufio.Reader, ed abstraction.EventDispatcher) {
+// tailedFile is the reading state of a single followed file.
+type tailedFile struct {
+	path   string
+	file   *os.File
+	reader *bufio.Reader
+	info   os.FileInfo
+	offset int64
+	// pending is the incomplete trailing line, kept until its line breaker is read
+	pending []byte
+	// skipping is set while the rest of a line exceeding MaxLogRecordSize is dropped
+	skipping bool
+	// record holds lines of the multiline record being collected
+	record     []string
+	recordSize int
+	// removedAt is when the path was found missing while the file was still open
+	removedAt time.Time
+}
+
+func (lf *LogFile) isGlob() bool {
+	return strings.ContainsAny(lf.filePath, "*?[")
+}
+
+// discover adds newly matched files to the followed set, in glob mode files that no longer match are drained and dropped.
+func (lf *LogFile) discover(ctx context.Context, ed abstraction.EventDispatcher, files map[string]*tailedFile, initial bool) {
+	if !lf.isGlob() {
+		if _, ok := files[lf.filePath]; !ok {
+			t := &tailedFile{path: lf.filePath}
+			files[lf.filePath] = t
+			lf.openAt(t, initial)
+		}
+		return
+	}
+	matches, err := filepath.Glob(lf.filePath)
+	if err != nil {
+		lf.logger.Error("invalid log file glob", zap.Error(err))
+		return
+	}
+	matched := make(map[string]bool, len(matches))
+	for _, path := range matches {
+		matched[path] = true
+		if _, ok := files[path]; ok {
+			continue
+		}
+		t := &tailedFile{path: path}
+		files[path] = t
+		lf.openAt(t, initial)
+	}
+	for path, t := range files {
+		if !matched[path] {
+			lf.read(ctx, ed, t)
+			lf.flush(ctx, ed, t)
+			lf.close(t)
+			delete(files, path)
+		}
+	}
+}
+
+// openAt opens the file if it exists, skipping its current content when atEnd is set.
+func (lf *LogFile) openAt(t *tailedFile, atEnd bool) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		lf.logger.Warn("log file does not exist yet, waiting for it", zap.String("path", t.path), zap.Error(err))
+		return
+	}
+	if err := lf.open(t, info); err != nil {
+		return
+	}
+	if !atEnd {
+		return
+	}
+	offset, err := t.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		lf.logger.Warn("failed to skip initial data", zap.String("path", t.path), zap.Error(err))
+		return
+	}
+	t.offset = offset
+}
+
+func (lf *LogFile) open(t *tailedFile, info os.FileInfo) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		lf.logger.Error("failed to open log file", zap.String("path", t.path), zap.Error(err))
+		return err
+	}
+	t.file, t.info, t.offset = file, info, 0
+	t.reader = bufio.NewReaderSize(file, logReadChunkSize)
+	t.pending, t.skipping = nil, false
+	return nil
+}
+
+func (lf *LogFile) close(t *tailedFile) {
+	if t.file == nil {
+		return
+	}
+	if err := t.file.Close(); err != nil {
+		lf.logger.Warn("failed to close log file", zap.String("path", t.path), zap.Error(err))
+	}
+	t.file = nil
+}
+
+// follow reads new content of the file, handling rotation (file replaced by a new one),
+// truncation (file shrunk below the read offset) and files that do not exist (yet).
+func (lf *LogFile) follow(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		lf.drainRemoved(ctx, ed, t)
+		return
+	}
+	t.removedAt = time.Time{}
+	switch {
+	case t.file == nil:
+		lf.logger.Info("log file appeared", zap.String("path", t.path))
+		if lf.open(t, info) != nil {
+			return
+		}
+	case !os.SameFile(t.info, info):
+		lf.logger.Info("log file rotated, reopening", zap.String("path", t.path))
+		lf.read(ctx, ed, t)
+		lf.flush(ctx, ed, t)
+		lf.close(t)
+		if lf.open(t, info) != nil {
+			return
+		}
+	case info.Size() < t.offset:
+		lf.logger.Info("log file truncated, rewinding", zap.String("path", t.path))
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			lf.logger.Error("failed to rewind log file", zap.String("path", t.path), zap.Error(err))
+			return
+		}
+		lf.flush(ctx, ed, t)
+		t.reader.Reset(t.file)
+		t.offset, t.pending, t.skipping = 0, nil, false
+	}
+	// a multiline record is complete once a check cycle passes without new data
+	if !lf.read(ctx, ed, t) {
+		lf.flush(ctx, ed, t)
+	}
+}
+
+// drainRemoved keeps reading a file that was rotated away and not recreated yet,
+// the file is closed once the rotation grace passes without new data.
+func (lf *LogFile) drainRemoved(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile) {
+	if t.file == nil {
+		return
+	}
+	if t.removedAt.IsZero() {
+		t.removedAt = time.Now()
+	}
+	if lf.read(ctx, ed, t) {
+		return
+	}
+	lf.flush(ctx, ed, t)
+	if time.Since(t.removedAt) < lf.rotationGrace {
+		return
+	}
+	lf.logger.Info("log file was not recreated, closing the rotated file", zap.String("path", t.path))
+	lf.close(t)
+}
+
+// read consumes newly appended data in chunks and reports whether there was any,
+// only the incomplete trailing line is kept between reads until it is completed.
+func (lf *LogFile) read(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile) bool {
+	if t.file == nil {
+		return false
+	}
+	chunk := make([]byte, logReadChunkSize)
+	consumed := false
 	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break // no new data yet
+		n, err := t.reader.Read(chunk)
+		if n > 0 {
+			consumed = true
+			t.offset += int64(n)
+			lf.consume(ctx, ed, t, chunk[:n])
 		}
 		if err != nil {
-			lf.logger.Error("failed reading log file", zap.Error(err))
+			if !errors.Is(err, io.EOF) {
+				lf.logger.Error("failed reading log file", zap.String("path", t.path), zap.Error(err))
+			}
+			return consumed
+		}
+		if n == 0 {
+			return consumed
+		}
+	}
+}
+
+// consume splits the chunk (following the pending line) into lines,
+// lines exceeding MaxLogRecordSize are skipped up to their line breaker.
+func (lf *LogFile) consume(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile, chunk []byte) {
+	breaker := []byte(lf.lineBreaker)
+	data := append(t.pending, chunk...)
+	t.pending = nil
+	if t.skipping {
+		next := bytes.Index(data, breaker)
+		if next < 0 {
+			// keeps a possibly split line breaker
+			t.pending = slices.Clone(data[max(0, len(data)-len(breaker)+1):])
 			return
 		}
-		line = strings.TrimRight(line, "\r\n")
-		if line == "" {
-			continue
+		data = data[next+len(breaker):]
+		t.skipping = false
+	}
+	if lastBreak := bytes.LastIndex(data, breaker); lastBreak >= 0 {
+		for line := range strings.SplitSeq(string(data[:lastBreak]), lf.lineBreaker) {
+			lf.collect(ctx, ed, t, line)
 		}
+		data = data[lastBreak+len(breaker):]
+	}
+	if len(data) > MaxLogRecordSize {
+		lf.logger.Warn(
+			"skipping a line exceeding the record size limit",
+			zap.String("path", t.path),
+			zap.Int("limit", MaxLogRecordSize),
+		)
+		t.skipping = true
+		t.pending = slices.Clone(data[len(data)-len(breaker)+1:])
+		return
+	}
+	t.pending = slices.Clone(data)
+}
 
-		if matches := lf.matcher.FindStringSubmatch(line); matches != nil {
-			event := NewMetaData("log-file", map[string]any{
-				"file":   lf.filePath,
-				"line":   line,
-				"groups": reshapeRegexpMatch(lf.matcher.SubexpNames(), matches),
-			})
-			ed.Emit(ctx, event)
-			global.IncMetric(
-				LogEventsMetricName,
-				LogEventsMetricHelp,
-				lf.metricLabels,
-			)
+// collect processes a complete line, or adds it to the current record in multiline mode.
+func (lf *LogFile) collect(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile, line string) {
+	if lf.lineBreaker == "\n" {
+		line = strings.TrimRight(line, "\r")
+	}
+	if len(line) > MaxLogRecordSize {
+		lf.logger.Warn("skipping a line exceeding the record size limit", zap.String("path", t.path), zap.Int("limit", MaxLogRecordSize))
+		return
+	}
+	if lf.multilineStart == nil {
+		lf.processRecord(ctx, ed, t.path, line)
+		return
+	}
+	if lf.multilineStart.MatchString(line) {
+		lf.flush(ctx, ed, t)
+	}
+	if len(t.record) == 0 && line == "" {
+		return
+	}
+	if t.recordSize+len(line) > MaxLogRecordSize {
+		lf.logger.Debug("dropping a line exceeding the record size limit", zap.String("path", t.path), zap.Int("limit", MaxLogRecordSize))
+		return
+	}
+	t.record = append(t.record, line)
+	t.recordSize += len(line) + 1
+}
+
+// flush processes the pending multiline record of the file.
+func (lf *LogFile) flush(ctx context.Context, ed abstraction.EventDispatcher, t *tailedFile) {
+	if len(t.record) == 0 {
+		return
+	}
+	record := strings.Join(t.record, "\n")
+	t.record, t.recordSize = nil, 0
+	lf.processRecord(ctx, ed, t.path, record)
+}
+
+func (lf *LogFile) processRecord(ctx context.Context, ed abstraction.EventDispatcher, path string, record string) {
+	if strings.TrimSpace(record) == "" {
+		return
+	}
+	matches := lf.matcher.FindStringSubmatch(record)
+	if matches == nil {
+		return
+	}
+	data := map[string]any{
+		"file":   path,
+		"line":   record,
+		"groups": reshapeRegexpMatch(lf.matcher.SubexpNames(), matches),
+	}
+	if lf.format != "" {
+		fields, err := logfields.Parse(lf.format, record)
+		if err != nil {
+			lf.logger.Debug("skipping malformed record", zap.String("path", path), zap.Error(err))
+			return
+		}
+		if !lf.conditions.Match(fields) {
+			return
 		}
+		data["fields"] = fields
 	}
+	event := NewMetaData("log-file", data)
+	ed.Emit(ctx, event)
+	global.IncMetric(
+		LogEventsMetricName,
+		LogEventsMetricHelp,
+		lf.metricLabels,
+	)
 }
 
 func reshapeRegexpMatch(keys, matches []string) map[string]string {
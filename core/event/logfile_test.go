@@ -0,0 +1,258 @@
+package event
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+)
+
+func startLogFile(t *testing.T, path string) <-chan map[string]any {
+	t.Helper()
+	lf, err := NewLogFile(path, "", "", "", "", nil, 10*time.Millisecond, zap.NewNop())
+	assert.NoError(t, err)
+	return runLogFile(t, lf)
+}
+
+func runLogFile(t *testing.T, lf *LogFile) <-chan map[string]any {
+	t.Helper()
+	received := make(chan map[string]any, 16)
+	ed := signals.NewSync[abstraction.Event]()
+	ed.AddListener(func(_ context.Context, e abstraction.Event) {
+		received <- e.GetData()
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lf.BuildTickChannel(ctx, ed)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// let the listener open the file and skip its current content
+	time.Sleep(50 * time.Millisecond)
+	return received
+}
+
+func appendLog(t *testing.T, path string, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
+
+func nextLine(t *testing.T, received <-chan map[string]any) string {
+	t.Helper()
+	data := nextEvent(t, received)
+	return data["line"].(string)
+}
+
+func TestLogFile_SkipsExistingContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "old line\n")
+	received := startLogFile(t, path)
+
+	appendLog(t, path, "new line\n")
+	assert.Equal(t, "new line", nextLine(t, received))
+	assertNoEvent(t, received)
+}
+
+func TestLogFile_PartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	received := startLogFile(t, path)
+
+	appendLog(t, path, "first ha")
+	time.Sleep(50 * time.Millisecond)
+	appendLog(t, path, "lf\nsecond\n")
+	assert.Equal(t, "first half", nextLine(t, received))
+	assert.Equal(t, "second", nextLine(t, received))
+}
+
+func TestLogFile_LongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	received := startLogFile(t, path)
+
+	// lines spanning several chunks are delivered whole
+	long := strings.Repeat("y", 3*logReadChunkSize+1)
+	appendLog(t, path, long+"\n")
+	assert.Equal(t, long, nextLine(t, received))
+
+	// lines exceeding the limit are skipped up to their line breaker, even across reads
+	for range 3 {
+		appendLog(t, path, strings.Repeat("x", MaxLogRecordSize/2))
+		time.Sleep(30 * time.Millisecond)
+	}
+	appendLog(t, path, "tail\nafter\n")
+	assert.Equal(t, "after", nextLine(t, received))
+	assertNoEvent(t, received)
+}
+
+func TestLogFile_WaitsForMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	received := startLogFile(t, path)
+
+	appendLog(t, path, "created later\n")
+	assert.Equal(t, "created later", nextLine(t, received))
+}
+
+func TestLogFile_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	received := startLogFile(t, path)
+
+	appendLog(t, path, "before rotation\n")
+	assert.Equal(t, "before rotation", nextLine(t, received))
+
+	assert.NoError(t, os.Rename(path, path+".1"))
+	appendLog(t, path+".1", "late write to rotated file\n")
+	time.Sleep(50 * time.Millisecond)
+	appendLog(t, path, "after rotation\n")
+	assert.Equal(t, "late write to rotated file", nextLine(t, received))
+	assert.Equal(t, "after rotation", nextLine(t, received))
+}
+
+func TestLogFile_ClosesRemovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	received := make(chan map[string]any, 16)
+	ed := signals.NewSync[abstraction.Event]()
+	ed.AddListener(func(_ context.Context, e abstraction.Event) {
+		received <- e.GetData()
+	})
+	lf, err := NewLogFile(path, "", "", "", "", nil, 10*time.Millisecond, zap.NewNop())
+	assert.NoError(t, err)
+	lf.rotationGrace = 50 * time.Millisecond
+	tailed := &tailedFile{path: path}
+	lf.openAt(tailed, true)
+
+	assert.NoError(t, os.Rename(path, path+".1"))
+	lf.follow(t.Context(), ed, tailed)
+	appendLog(t, path+".1", "late write to rotated file\n")
+	lf.follow(t.Context(), ed, tailed)
+	assert.Equal(t, "late write to rotated file", nextLine(t, received))
+	assert.NotZero(t, tailed.file)
+
+	time.Sleep(lf.rotationGrace)
+	lf.follow(t.Context(), ed, tailed)
+	assert.Zero(t, tailed.file)
+
+	appendLog(t, path, "recreated\n")
+	lf.follow(t.Context(), ed, tailed)
+	assert.Equal(t, "recreated", nextLine(t, received))
+}
+
+func TestLogFile_Truncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	received := startLogFile(t, path)
+
+	appendLog(t, path, "a rather long line before truncation\n")
+	assert.Equal(t, "a rather long line before truncation", nextLine(t, received))
+
+	assert.NoError(t, os.Truncate(path, 0))
+	time.Sleep(50 * time.Millisecond)
+	appendLog(t, path, "short\n")
+	assert.Equal(t, "short", nextLine(t, received))
+}
+
+func TestLogFile_Glob(t *testing.T) {
+	dir := t.TempDir()
+	appendLog(t, filepath.Join(dir, "api.log"), "")
+	received := startLogFile(t, filepath.Join(dir, "*.log"))
+
+	appendLog(t, filepath.Join(dir, "api.log"), "from api\n")
+	data := nextEvent(t, received)
+	assert.Equal[any](t, filepath.Join(dir, "api.log"), data["file"])
+
+	appendLog(t, filepath.Join(dir, "worker.log"), "from worker\n")
+	data = nextEvent(t, received)
+	assert.Equal[any](t, filepath.Join(dir, "worker.log"), data["file"])
+	assert.Equal[any](t, "from worker", data["line"])
+
+	appendLog(t, filepath.Join(dir, "ignored.txt"), "ignored\n")
+	assertNoEvent(t, received)
+}
+
+func TestLogFile_LineBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	lf, err := NewLogFile(path, `\r\n`, "", "", "", nil, 10*time.Millisecond, zap.NewNop())
+	assert.NoError(t, err)
+	received := runLogFile(t, lf)
+
+	appendLog(t, path, "first\nstill first\r\nsecond\r\n")
+	assert.Equal(t, "first\nstill first", nextLine(t, received))
+	assert.Equal(t, "second", nextLine(t, received))
+}
+
+func TestLogFile_Multiline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	lf, err := NewLogFile(path, "", "Exception", `^\d{4}-\d{2}-\d{2} `, "", nil, 10*time.Millisecond, zap.NewNop())
+	assert.NoError(t, err)
+	received := runLogFile(t, lf)
+
+	appendLog(t, path, "2024-01-01 ERROR NullPointerException\n\tat a.b(C.java:1)\n")
+	appendLog(t, path, "\tat d.e(F.java:2)\n2024-01-01 INFO ok\n2024-01-01 ERROR IOException\n")
+	assert.Equal(t, "2024-01-01 ERROR NullPointerException\n\tat a.b(C.java:1)\n\tat d.e(F.java:2)", nextLine(t, received))
+	// last record is flushed once the file stays idle
+	assert.Equal(t, "2024-01-01 ERROR IOException", nextLine(t, received))
+	assertNoEvent(t, received)
+}
+
+func TestLogFile_StructuredConditions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	lf, err := NewLogFile(
+		path, "", "", "", "json",
+		[]string{`level == "error"`, `service =~ "api.*"`},
+		10*time.Millisecond,
+		zap.NewNop(),
+	)
+	assert.NoError(t, err)
+	received := runLogFile(t, lf)
+
+	appendLog(t, path, `{"level":"info","service":"api-gw","msg":"ok"}`+"\n")
+	appendLog(t, path, `not json`+"\n")
+	appendLog(t, path, `{"level":"error","service":"worker","msg":"boom"}`+"\n")
+	appendLog(t, path, `{"level":"error","service":"api-gw","msg":"failed"}`+"\n")
+	data := nextEvent(t, received)
+	assert.Equal(t, map[string]any{"level": "error", "service": "api-gw", "msg": "failed"}, data["fields"].(map[string]any))
+	assertNoEvent(t, received)
+}
+
+func TestLogFile_Logfmt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+	lf, err := NewLogFile(path, "", "", "", "logfmt", []string{`level != debug`}, 10*time.Millisecond, zap.NewNop())
+	assert.NoError(t, err)
+	received := runLogFile(t, lf)
+
+	appendLog(t, path, "level=debug msg=noise\nlevel=warn msg=\"disk almost full\"\n")
+	data := nextEvent(t, received)
+	assert.Equal(t, "disk almost full", data["fields"].(map[string]any)["msg"])
+	assertNoEvent(t, received)
+}
+
+func TestNewLogFile_Invalid(t *testing.T) {
+	_, err := NewLogFile("app.log", "", "", "(", "", nil, 0, zap.NewNop())
+	assert.Error(t, err)
+	_, err = NewLogFile("app.log", "", "", "", "xml", nil, 0, zap.NewNop())
+	assert.Error(t, err)
+	_, err = NewLogFile("app.log", "", "", "", "json", []string{"level"}, 0, zap.NewNop())
+	assert.Error(t, err)
+}
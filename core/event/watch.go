@@ -0,0 +1,338 @@
+package event
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+const (
+	WatchEventsMetricName = "watch"
+	WatchEventsMetricHelp = "amount of events dispatched using filesystem watch"
+)
+
+var (
+	watchOps = map[string]fsnotify.Op{
+		"create": fsnotify.Create,
+		"write":  fsnotify.Write,
+		"remove": fsnotify.Remove,
+		"rename": fsnotify.Rename,
+		"chmod":  fsnotify.Chmod,
+	}
+	watchOpNames = []string{"create", "write", "remove", "rename", "chmod"}
+)
+
+// delays between attempts to watch directories that did not exist at startup.
+const (
+	watchRetryDelay    = time.Second
+	watchMaxRetryDelay = time.Minute
+)
+
+func init() {
+	eg.Register(newWatchGenerator)
+}
+
+func newWatchGenerator(log *zap.Logger, cfg *config.JobEvent) (abstraction.EventGenerator, bool) {
+	if cfg.Watch == nil {
+		return nil, false
+	}
+	return NewWatch(cfg.Watch, log), true
+}
+
+// Watch emits an event for every filesystem change matching its paths,
+// bursts of changes on a single path are merged into one event using the debounce duration.
+type Watch struct {
+	targets      []watchTarget
+	recursive    bool
+	ops          fsnotify.Op
+	debounce     time.Duration
+	log          *zap.Logger
+	metricLabels prometheus.Labels
+}
+
+// watchTarget is a watched directory alongside the pattern that file names inside it must match.
+type watchTarget struct {
+	dir     string
+	pattern string
+}
+
+func NewWatch(cfg *config.WatchEvent, logger *zap.Logger) *Watch {
+	var ops fsnotify.Op
+	for _, op := range cfg.Ops {
+		ops |= watchOps[strings.ToLower(op)]
+	}
+	if ops == 0 {
+		ops = fsnotify.Create | fsnotify.Write | fsnotify.Remove | fsnotify.Rename | fsnotify.Chmod
+	}
+	metricLabels := prometheus.Labels{
+		"paths": strings.Join(cfg.Paths, ","),
+		"ops":   ops.String(),
+	}
+	global.RegisterCounter(
+		WatchEventsMetricName,
+		WatchEventsMetricHelp,
+		metricLabels,
+	)
+	return &Watch{
+		targets:   resolveWatchTargets(cfg.Paths),
+		recursive: cfg.Recursive,
+		ops:       ops,
+		debounce:  cfg.Debounce,
+		log: logger.With(
+			zap.String("scheduler", "watch"),
+			zap.Strings("paths", cfg.Paths),
+			zap.Bool("recursive", cfg.Recursive),
+			zap.Stringer("ops", ops),
+		),
+		metricLabels: metricLabels,
+	}
+}
+
+// resolveWatchTargets splits paths into directories to watch and name patterns,
+// directories are watched entirely while files and globs are watched through their parent directory
+// so files that do not exist yet are noticed too.
+func resolveWatchTargets(paths []string) []watchTarget {
+	targets := make([]watchTarget, 0, len(paths))
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			targets = append(targets, watchTarget{dir: path, pattern: "*"})
+			continue
+		}
+		targets = append(targets, watchTarget{dir: filepath.Dir(path), pattern: filepath.Base(path)})
+	}
+	return targets
+}
+
+// BuildTickChannel implements abstraction.EventGenerator.
+func (w *Watch) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		w.log.Error("failed to create filesystem watcher", zap.Error(err))
+		return
+	}
+	defer func() {
+		if cerr := watcher.Close(); cerr != nil {
+			w.log.Warn("failed to close filesystem watcher", zap.Error(cerr))
+		}
+	}()
+	missing := w.addTargets(watcher, w.targets)
+	for _, target := range missing {
+		w.log.Warn("watched directory is not available, retrying until it is created", zap.String("dir", target.dir))
+	}
+	var retry <-chan time.Time
+	retryDelay := watchRetryDelay
+	if len(missing) != 0 {
+		retry = time.After(retryDelay)
+	}
+
+	pending := newDebouncer(w.debounce, func(path string, ops fsnotify.Op, last fsnotify.Op) {
+		w.emit(ctx, ed, path, ops, last)
+	})
+	defer pending.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry:
+			missing = w.addTargets(watcher, missing)
+			retry = nil
+			if len(missing) != 0 {
+				retryDelay = min(retryDelay*2, watchMaxRetryDelay)
+				retry = time.After(retryDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			w.log.Warn("filesystem watcher error", zap.Error(err))
+		case e, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if w.recursive && e.Has(fsnotify.Create) {
+				if info, err := os.Stat(e.Name); err == nil && info.IsDir() && w.watched(e.Name) {
+					if err := w.add(watcher, e.Name); err != nil {
+						w.log.Warn("failed to watch directory", zap.String("dir", e.Name), zap.Error(err))
+					}
+				}
+			}
+			if e.Op&w.ops == 0 || !w.matches(e.Name) {
+				continue
+			}
+			pending.push(e.Name, e.Op&w.ops)
+		}
+	}
+}
+
+// addTargets watches directories of targets, targets whose directory cannot be watched are returned.
+func (w *Watch) addTargets(watcher *fsnotify.Watcher, targets []watchTarget) []watchTarget {
+	var missing []watchTarget
+	for _, target := range targets {
+		if err := w.add(watcher, target.dir); err != nil {
+			w.log.Debug("cannot watch directory", zap.String("dir", target.dir), zap.Error(err))
+			missing = append(missing, target)
+			continue
+		}
+		w.log.Debug("watching directory", zap.String("dir", target.dir))
+	}
+	return missing
+}
+
+// add watches dir, and its sub-directories in recursive mode.
+// Only failing to watch dir itself is returned, failures of sub-directories are logged.
+func (w *Watch) add(watcher *fsnotify.Watcher, dir string) error {
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+	if !w.recursive {
+		return nil
+	}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir {
+			if err := watcher.Add(path); err != nil {
+				w.log.Warn("failed to watch directory", zap.String("dir", path), zap.Error(err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		w.log.Warn("failed to walk directory", zap.String("dir", dir), zap.Error(err))
+	}
+	return nil
+}
+
+// watched reports whether the directory is inside one of the watched trees.
+func (w *Watch) watched(dir string) bool {
+	for _, target := range w.targets {
+		if isNested(target.dir, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the path belongs to one of the targets,
+// in recursive mode files of nested directories are matched against the pattern as well.
+func (w *Watch) matches(path string) bool {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	for _, target := range w.targets {
+		if dir != target.dir && (!w.recursive || !isNested(target.dir, dir)) {
+			continue
+		}
+		if ok, _ := filepath.Match(target.pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isNested(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+func (w *Watch) emit(ctx context.Context, ed abstraction.EventDispatcher, path string, ops fsnotify.Op, last fsnotify.Op) {
+	names := make([]string, 0, len(watchOpNames))
+	for _, name := range watchOpNames {
+		if ops.Has(watchOps[name]) {
+			names = append(names, name)
+		}
+	}
+	w.log.Debug("filesystem change", zap.String("path", path), zap.Strings("ops", names))
+	ed.Emit(ctx, NewMetaData("watch", map[string]any{
+		"path": path,
+		"name": filepath.Base(path),
+		"dir":  filepath.Dir(path),
+		"op":   opName(last),
+		"ops":  names,
+	}))
+	global.IncMetric(
+		WatchEventsMetricName,
+		WatchEventsMetricHelp,
+		w.metricLabels,
+	)
+}
+
+func opName(op fsnotify.Op) string {
+	for _, name := range watchOpNames {
+		if op.Has(watchOps[name]) {
+			return name
+		}
+	}
+	return strings.ToLower(op.String())
+}
+
+// debouncer merges operations on a single path until no new operation is received for the debounce duration.
+type debouncer struct {
+	delay   time.Duration
+	fire    func(path string, ops fsnotify.Op, last fsnotify.Op)
+	mu      sync.Mutex
+	pending map[string]*pendingChange
+}
+
+type pendingChange struct {
+	ops   fsnotify.Op
+	last  fsnotify.Op
+	timer *time.Timer
+}
+
+func newDebouncer(delay time.Duration, fire func(string, fsnotify.Op, fsnotify.Op)) *debouncer {
+	return &debouncer{
+		delay:   delay,
+		fire:    fire,
+		pending: make(map[string]*pendingChange),
+	}
+}
+
+func (d *debouncer) push(path string, op fsnotify.Op) {
+	if d.delay == 0 {
+		d.fire(path, op, op)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// once the timer has fired the change is (being) delivered, so the operation starts a new one
+	if change, ok := d.pending[path]; ok && change.timer.Stop() {
+		change.ops |= op
+		change.last = op
+		change.timer.Reset(d.delay)
+		return
+	}
+	change := &pendingChange{ops: op, last: op}
+	change.timer = time.AfterFunc(d.delay, func() {
+		d.mu.Lock()
+		if d.pending[path] == change {
+			delete(d.pending, path)
+		}
+		ops, last := change.ops, change.last
+		d.mu.Unlock()
+		d.fire(path, ops, last)
+	})
+	d.pending[path] = change
+}
+
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for path, change := range d.pending {
+		change.timer.Stop()
+		delete(d.pending, path)
+	}
+}
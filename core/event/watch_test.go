@@ -0,0 +1,183 @@
+package event
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/fsnotify/fsnotify"
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+func startWatch(t *testing.T, cfg *config.WatchEvent) <-chan map[string]any {
+	t.Helper()
+	received := make(chan map[string]any, 16)
+	ed := signals.NewSync[abstraction.Event]()
+	ed.AddListener(func(_ context.Context, e abstraction.Event) {
+		received <- e.GetData()
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewWatch(cfg, zap.NewNop()).BuildTickChannel(ctx, ed)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// give the watcher a moment to register its directories
+	time.Sleep(100 * time.Millisecond)
+	return received
+}
+
+func nextEvent(t *testing.T, received <-chan map[string]any) map[string]any {
+	t.Helper()
+	select {
+	case data := <-received:
+		return data
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, received <-chan map[string]any) {
+	t.Helper()
+	select {
+	case data := <-received:
+		t.Fatalf("unexpected watch event: %v", data)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatch_GlobAndOps(t *testing.T) {
+	dir := t.TempDir()
+	received := startWatch(t, &config.WatchEvent{
+		Paths: []string{filepath.Join(dir, "*.csv")},
+		Ops:   []string{"create"},
+	})
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("x"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte("x"), 0o600))
+
+	data := nextEvent(t, received)
+	assert.Equal[any](t, filepath.Join(dir, "data.csv"), data["path"])
+	assert.Equal[any](t, "data.csv", data["name"])
+	assert.Equal[any](t, "create", data["op"])
+	assertNoEvent(t, received)
+}
+
+func TestWatch_Debounce(t *testing.T) {
+	dir := t.TempDir()
+	received := startWatch(t, &config.WatchEvent{
+		Paths:    []string{dir},
+		Ops:      []string{"create", "write"},
+		Debounce: 150 * time.Millisecond,
+	})
+
+	file := filepath.Join(dir, "burst.log")
+	f, err := os.Create(file)
+	assert.NoError(t, err)
+	for range 5 {
+		_, err = f.WriteString("line\n")
+		assert.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, f.Close())
+
+	data := nextEvent(t, received)
+	assert.Equal[any](t, file, data["path"])
+	assert.Equal[any](t, []string{"create", "write"}, data["ops"])
+	assertNoEvent(t, received)
+}
+
+func TestWatch_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	received := startWatch(t, &config.WatchEvent{
+		Paths:     []string{filepath.Join(dir, "*.csv")},
+		Recursive: true,
+		Ops:       []string{"create"},
+	})
+
+	nested := filepath.Join(dir, "2024", "01")
+	assert.NoError(t, os.MkdirAll(nested, 0o700))
+	// wait for the new directories to be watched
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(filepath.Join(nested, "data.csv"), []byte("x"), 0o600))
+
+	data := nextEvent(t, received)
+	assert.Equal[any](t, filepath.Join(nested, "data.csv"), data["path"])
+}
+
+func TestWatch_MissingDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "later")
+	received := startWatch(t, &config.WatchEvent{
+		Paths: []string{filepath.Join(dir, "*.csv")},
+		Ops:   []string{"create"},
+	})
+
+	assert.NoError(t, os.Mkdir(dir, 0o700))
+	// wait for the first retry to watch the directory
+	time.Sleep(watchRetryDelay + 200*time.Millisecond)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte("x"), 0o600))
+
+	data := nextEvent(t, received)
+	assert.Equal[any](t, filepath.Join(dir, "data.csv"), data["path"])
+}
+
+// TestDebouncer_PushRacingFire pushes a second operation right when the timer of the first one fires,
+// each operation must be delivered exactly once whether it is merged or starts a new change.
+func TestDebouncer_PushRacingFire(t *testing.T) {
+	const delay = time.Millisecond
+	var mu sync.Mutex
+	var fired []fsnotify.Op
+	d := newDebouncer(delay, func(_ string, ops fsnotify.Op, _ fsnotify.Op) {
+		mu.Lock()
+		defer mu.Unlock()
+		fired = append(fired, ops)
+	})
+	defer d.stop()
+
+	for i := range 100 {
+		mu.Lock()
+		fired = nil
+		mu.Unlock()
+
+		d.push("file", fsnotify.Create)
+		time.Sleep(delay + time.Duration(i%10)*50*time.Microsecond)
+		d.push("file", fsnotify.Write)
+
+		count := func() (creates, writes int) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, ops := range fired {
+				if ops.Has(fsnotify.Create) {
+					creates++
+				}
+				if ops.Has(fsnotify.Write) {
+					writes++
+				}
+			}
+			return creates, writes
+		}
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(delay) {
+			if _, writes := count(); writes != 0 {
+				break
+			}
+		}
+		// leaves room for a duplicated delivery
+		time.Sleep(10 * delay)
+		creates, writes := count()
+		assert.Equal(t, 1, creates, "create delivered %d times", creates)
+		assert.Equal(t, 1, writes, "write delivered %d times", writes)
+	}
+}
@@ -44,10 +44,8 @@ func NewWebEventListener(event string) abstraction.EventGenerator {
 }
 
 // BuildTickChannel implements abstraction.Scheduler.
-func (w *WebEventListener) BuildTickChannel(ed abstraction.EventDispatcher) {
-	ctx, cancel := context.WithCancel(global.CTX())
-	defer cancel()
-	global.CTX().AddEventListener(
+func (w *WebEventListener) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
+	remove := global.CTX().AddEventListener(
 		w.event, func(params map[string]any) {
 			event := NewMetaData(
 				"web",
@@ -63,5 +61,6 @@ func (w *WebEventListener) BuildTickChannel(ed abstraction.EventDispatcher) {
 			ed.Emit(ctx, event)
 		},
 	)
+	defer remove()
 	<-ctx.Done()
 }
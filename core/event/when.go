@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"maps"
+
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/core/when"
+)
+
+// conditional drops events of the wrapped generator that do not satisfy the `when` condition.
+type conditional struct {
+	abstraction.EventGenerator
+	cond *when.Condition
+	log  *zap.Logger
+}
+
+// BuildTickChannel implements abstraction.EventGenerator.
+func (c *conditional) BuildTickChannel(ctx context.Context, ed abstraction.EventDispatcher) {
+	filtered := signals.NewSync[abstraction.Event]()
+	filtered.AddListener(func(ctx context.Context, e abstraction.Event) {
+		if c.accepts(e) {
+			ed.Emit(ctx, e)
+		}
+	})
+	c.EventGenerator.BuildTickChannel(ctx, filtered)
+}
+
+func (c *conditional) accepts(e abstraction.Event) bool {
+	data := make(map[string]any)
+	maps.Copy(data, e.GetData())
+	ok, err := c.cond.Eval(data)
+	if err != nil {
+		c.log.Warn("failed to evaluate when condition, dropping the event", zap.Error(err))
+		return false
+	}
+	if !ok {
+		c.log.Debug("when condition did not hold, dropping the event", zap.Any("event", data))
+	}
+	return ok
+}
@@ -0,0 +1,103 @@
+// Package expect implements assertions over responses of http tasks.
+package expect
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rules is a compiled set of assertions, a response must satisfy all of them.
+type Rules struct {
+	statuses []statusRange
+	body     *regexp.Regexp
+	json     []*jsonCheck
+}
+
+type statusRange struct {
+	from, to int
+}
+
+// New compiles the rules, statuses can be exact codes (`200`), ranges (`200-299`) or classes (`2xx`).
+// Without any status rule every status below 400 is accepted.
+func New(statuses []string, body string, json []string) (*Rules, error) {
+	r := &Rules{}
+	for _, s := range statuses {
+		sr, err := parseStatus(s)
+		if err != nil {
+			return nil, err
+		}
+		r.statuses = append(r.statuses, sr)
+	}
+	if len(r.statuses) == 0 {
+		r.statuses = []statusRange{{from: 0, to: 399}}
+	}
+	if body != "" {
+		re, err := regexp.Compile(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body expectation: %w", err)
+		}
+		r.body = re
+	}
+	for _, expr := range json {
+		check, err := parseJSONCheck(expr)
+		if err != nil {
+			return nil, err
+		}
+		r.json = append(r.json, check)
+	}
+	return r, nil
+}
+
+// Check returns an error describing every violated rule, or nil if the response satisfies all of them.
+func (r *Rules) Check(status int, body []byte) error {
+	errs := make([]error, 0)
+	if !r.statusAllowed(status) {
+		errs = append(errs, fmt.Errorf("unexpected status code %d", status))
+	}
+	if r.body != nil && !r.body.Match(body) {
+		errs = append(errs, fmt.Errorf("body does not match `%s`", r.body))
+	}
+	for _, check := range r.json {
+		if err := check.evaluate(body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Rules) statusAllowed(status int) bool {
+	for _, sr := range r.statuses {
+		if status >= sr.from && status <= sr.to {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStatus(spec string) (statusRange, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	invalid := fmt.Errorf("invalid status expectation `%s`, expected a code (200), a range (200-299) or a class (2xx)", spec)
+	if len(spec) == 3 && strings.HasSuffix(spec, "xx") {
+		class, err := strconv.Atoi(spec[:1])
+		if err != nil || class < 1 || class > 5 {
+			return statusRange{}, invalid
+		}
+		return statusRange{from: class * 100, to: class*100 + 99}, nil
+	}
+	from, to, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		to = from
+	}
+	f, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return statusRange{}, invalid
+	}
+	t, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil || t < f {
+		return statusRange{}, invalid
+	}
+	return statusRange{from: f, to: t}, nil
+}
@@ -0,0 +1,76 @@
+package expect_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/expect"
+)
+
+func TestRules_DefaultStatus(t *testing.T) {
+	rules, err := expect.New(nil, "", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, rules.Check(200, nil))
+	assert.NoError(t, rules.Check(302, nil))
+	assert.Error(t, rules.Check(404, nil))
+	assert.Error(t, rules.Check(500, nil))
+}
+
+func TestRules_Status(t *testing.T) {
+	rules, err := expect.New([]string{"204", "300-302", "4xx"}, "", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, rules.Check(204, nil))
+	assert.NoError(t, rules.Check(301, nil))
+	assert.NoError(t, rules.Check(418, nil))
+	assert.Error(t, rules.Check(200, nil))
+	assert.Error(t, rules.Check(500, nil))
+}
+
+func TestRules_InvalidStatus(t *testing.T) {
+	for _, spec := range []string{"ok", "299-200", "9xx"} {
+		_, err := expect.New([]string{spec}, "", nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestRules_Body(t *testing.T) {
+	rules, err := expect.New(nil, `healthy`, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, rules.Check(200, []byte("all healthy")))
+	assert.Error(t, rules.Check(200, []byte("degraded")))
+
+	_, err = expect.New(nil, `(`, nil)
+	assert.Error(t, err)
+}
+
+func TestRules_JSON(t *testing.T) {
+	rules, err := expect.New(nil, "", []string{
+		`$.status == "ok"`,
+		`$.healthy == true`,
+		`$.checks[1].latency == 12`,
+		`$["app.version"] != "0.0.0"`,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, rules.Check(200, []byte(`{
+		"status": "ok",
+		"healthy": true,
+		"checks": [{"latency": 3}, {"latency": 12}],
+		"app.version": "1.2.0"
+	}`)))
+
+	err = rules.Check(200, []byte(`{"status":"ok","healthy":false,"checks":[],"app.version":"0.0.0"}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "$.healthy == true")
+	assert.Contains(t, err.Error(), "path not found")
+	assert.Contains(t, err.Error(), "app.version")
+
+	assert.Error(t, rules.Check(200, []byte("not json")))
+}
+
+func TestRules_InvalidJSONExpression(t *testing.T) {
+	for _, expr := range []string{`status == "ok"`, `$.status`, `$.items[x] == 1`} {
+		_, err := expect.New(nil, "", []string{expr})
+		assert.Error(t, err)
+	}
+}
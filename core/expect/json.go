@@ -0,0 +1,142 @@
+package expect
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonCheck is a comparison between a value selected from a json document and a literal,
+// e.g. `$.status == "ok"` or `$.items[0].healthy != false`.
+type jsonCheck struct {
+	expr   string
+	path   []any
+	negate bool
+	want   any
+}
+
+func parseJSONCheck(expr string) (*jsonCheck, error) {
+	idx, negate := operatorIndex(expr)
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid json expectation `%s`, expected `<path> == <value>` or `<path> != <value>`", expr)
+	}
+	path, err := parsePath(strings.TrimSpace(expr[:idx]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid json expectation `%s`: %w", expr, err)
+	}
+	return &jsonCheck{
+		expr:   expr,
+		path:   path,
+		negate: negate,
+		want:   parseLiteral(strings.TrimSpace(expr[idx+2:])),
+	}, nil
+}
+
+// operatorIndex finds the first `==` or `!=` outside of quoted path segments.
+func operatorIndex(expr string) (int, bool) {
+	quoted := false
+	for i := 0; i+1 < len(expr); i++ {
+		switch {
+		case expr[i] == '"':
+			quoted = !quoted
+		case quoted:
+		case expr[i:i+2] == "==":
+			return i, false
+		case expr[i:i+2] == "!=":
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// parsePath parses paths like `$.a.b[0]["c.d"]` into a list of keys (string) and indexes (int).
+func parsePath(path string) ([]any, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with `$`, received `%s`", path)
+	}
+	segments := make([]any, 0)
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path `%s`", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated `[` in path `%s`", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if key, err := strconv.Unquote(inner); err == nil {
+				segments = append(segments, key)
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index `%s` in path `%s`", inner, path)
+			}
+			segments = append(segments, index)
+		default:
+			return nil, fmt.Errorf("unexpected `%c` in path `%s`", rest[0], path)
+		}
+	}
+	return segments, nil
+}
+
+// parseLiteral decodes json literals (`"ok"`, `1`, `true`, `null`), anything else is used as a plain string.
+func parseLiteral(src string) any {
+	var value any
+	if err := json.Unmarshal([]byte(src), &value); err != nil {
+		return strings.Trim(src, "'")
+	}
+	return value
+}
+
+func (c *jsonCheck) evaluate(body []byte) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("`%s`: body is not a valid json: %w", c.expr, err)
+	}
+	got, ok := lookup(doc, c.path)
+	if !ok {
+		return fmt.Errorf("`%s`: path not found", c.expr)
+	}
+	if reflect.DeepEqual(got, c.want) == c.negate {
+		return fmt.Errorf("`%s`: received `%v`", c.expr, got)
+	}
+	return nil
+}
+
+func lookup(doc any, path []any) (any, bool) {
+	current := doc
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case string:
+			obj, ok := current.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if current, ok = obj[s]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := current.([]any)
+			if !ok || s < 0 || s >= len(arr) {
+				return nil, false
+			}
+			current = arr[s]
+		}
+	}
+	return current, true
+}
@@ -4,10 +4,12 @@ package global
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
 	"reflect"
 	"sync"
+	"syscall"
 
 	"github.com/fmotalleb/go-tools/log"
 	"go.uber.org/zap"
@@ -26,10 +28,12 @@ func CTX() *Context {
 var c = sync.OnceValue(newGlobalContext)
 
 type (
-	EventListenerMap = map[string][]func(map[string]any)
+	// EventListenerMap holds listeners of web events by event name and listener id.
+	EventListenerMap = map[string]map[uint64]func(map[string]any)
 	Context          struct {
 		context.Context
-		mu *sync.RWMutex
+		mu             *sync.RWMutex
+		nextListenerID uint64
 	}
 )
 
@@ -39,7 +43,8 @@ func newGlobalContext() *Context {
 	if err != nil {
 		panic(fmt.Errorf("failed to initialize logger: %w", err))
 	}
-	ctx, _ = signal.NotifyContext(ctx, os.Interrupt, os.Kill)
+	// SIGTERM is sent by `docker stop`, SIGKILL cannot be caught
+	ctx, _ = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx = context.WithValue(
 		ctx,
 		ctxutils.EventListeners,
@@ -51,19 +56,41 @@ func newGlobalContext() *Context {
 	}
 }
 
+// EventListeners returns a snapshot of registered listeners.
 func (c *Context) EventListeners() EventListenerMap {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	listeners := c.Value(ctxutils.EventListeners)
-	return listeners.(EventListenerMap)
+	listeners := c.Context.Value(ctxutils.EventListeners).(EventListenerMap)
+	snapshot := make(EventListenerMap, len(listeners))
+	for event, l := range listeners {
+		snapshot[event] = maps.Clone(l)
+	}
+	return snapshot
 }
 
-func (c *Context) AddEventListener(event string, listener func(map[string]any)) {
+// AddEventListener registers the listener of the event, the returned function removes it.
+func (c *Context) AddEventListener(event string, listener func(map[string]any)) func() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	listeners := c.Value(ctxutils.EventListeners).(EventListenerMap)
-	listeners[event] = append(listeners[event], listener)
-	c.Context = context.WithValue(c.Context, ctxutils.EventListeners, listeners)
+	listeners := c.Context.Value(ctxutils.EventListeners).(EventListenerMap)
+	if listeners[event] == nil {
+		listeners[event] = make(map[uint64]func(map[string]any))
+	}
+	c.nextListenerID++
+	id := c.nextListenerID
+	listeners[event][id] = listener
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(listeners[event], id)
+	}
+}
+
+// Value implements context.Context, the context is replaced by Put so it is read under the lock.
+func (c *Context) Value(key any) any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Context.Value(key)
 }
 
 func getTypename[T any](item T) string {
@@ -12,10 +12,12 @@ import (
 )
 
 const (
-	OKMetricName  = "done_tasks"
-	OKMetricHelp  = "Amount of done tasks (with ok status)"
-	ErrMetricName = "failed_tasks"
-	ErrMetricHelp = "Amount of failed tasks"
+	OKMetricName   = "done_tasks"
+	OKMetricHelp   = "Amount of done tasks (with ok status)"
+	ErrMetricName  = "failed_tasks"
+	ErrMetricHelp  = "Amount of failed tasks"
+	SkipMetricName = "skipped_tasks"
+	SkipMetricHelp = "Amount of tasks skipped because their when condition did not hold"
 
 	namespace = "crontab_go"
 )
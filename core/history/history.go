@@ -0,0 +1,246 @@
+// Package history persists runs of jobs and their tasks, alongside the last ticks of cron schedules,
+// into an embedded database under the state directory.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// FileName is the name of the database file inside the state directory.
+	FileName = "history.db"
+
+	DefaultMaxRuns   = 1000
+	DefaultMaxOutput = 4096
+
+	truncatedMark = "[truncated] "
+)
+
+// Status of a job or task run.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailure Status = "failure"
+	StatusSkipped Status = "skipped"
+	// StatusTimedOut is a failure of a task caused by its timeout.
+	StatusTimedOut Status = "timed-out"
+)
+
+var (
+	jobsBucket  = []byte("jobs")
+	ticksBucket = []byte("ticks")
+)
+
+// JobRun is a single run of a job, triggered by one event.
+type JobRun struct {
+	ID     uint64         `json:"id"`
+	Job    string         `json:"job"`
+	Event  map[string]any `json:"event,omitempty"`
+	Start  time.Time      `json:"start"`
+	End    time.Time      `json:"end"`
+	Status Status         `json:"status"`
+	Tasks  []TaskRun      `json:"tasks,omitempty"`
+}
+
+// TaskRun is the outcome of a single task within a job run.
+type TaskRun struct {
+	Name       string    `json:"name"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Status     Status    `json:"status"`
+	ExitCode   int       `json:"exit-code"`
+	StatusCode int       `json:"status-code,omitempty"`
+	Attempts   int       `json:"attempts"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Query filters runs returned by Store.Runs.
+type Query struct {
+	// Status limits the result to runs with this status, empty means any
+	Status Status
+	// Limit is the maximum amount of runs returned, zero means no limit
+	Limit int
+}
+
+// Store is the run history database, a nil store ignores records and has no runs.
+type Store struct {
+	db        *bolt.DB
+	maxRuns   uint64
+	maxOutput int
+}
+
+// Open opens (or creates) the history database inside dir, keeping the latest maxRuns runs of each job
+// and the last maxOutput bytes of output of each task, zero values fall back to defaults.
+func Open(dir string, maxRuns uint, maxOutput uint) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	db, err := bolt.Open(filepath.Join(dir, FileName), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{jobsBucket, ticksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to initialize history database: %w", err), db.Close())
+	}
+	if maxRuns == 0 {
+		maxRuns = DefaultMaxRuns
+	}
+	if maxOutput == 0 {
+		maxOutput = DefaultMaxOutput
+	}
+	return &Store{
+		db:        db,
+		maxRuns:   uint64(maxRuns),
+		maxOutput: int(maxOutput),
+	}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Record stores the run (assigning its ID) and drops the oldest runs of the job beyond the retention limit.
+func (s *Store) Record(run *JobRun) error {
+	if s == nil {
+		return nil
+	}
+	for i := range run.Tasks {
+		run.Tasks[i].Output = truncate(run.Tasks[i].Output, s.maxOutput)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(jobsBucket).CreateBucketIfNotExists([]byte(run.Job))
+		if err != nil {
+			return err
+		}
+		if run.ID, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		value, err := json.Marshal(run)
+		if err != nil {
+			return fmt.Errorf("failed to encode run: %w", err)
+		}
+		if err := bucket.Put(key(run.ID), value); err != nil {
+			return err
+		}
+		if run.ID <= s.maxRuns {
+			return nil
+		}
+		oldest := run.ID - s.maxRuns
+		expired := [][]byte{}
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil && binary.BigEndian.Uint64(k) <= oldest; k, _ = cursor.Next() {
+			expired = append(expired, k)
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Runs returns runs of the job matching the query, newest first.
+func (s *Store) Runs(job string, q Query) ([]JobRun, error) {
+	runs := []JobRun{}
+	if s == nil {
+		return runs, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(jobsBucket).Bucket([]byte(job))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var run JobRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return fmt.Errorf("failed to decode run %d of job %s: %w", binary.BigEndian.Uint64(k), job, err)
+			}
+			if q.Status != "" && run.Status != q.Status {
+				continue
+			}
+			runs = append(runs, run)
+			if q.Limit > 0 && len(runs) >= q.Limit {
+				return nil
+			}
+		}
+		return nil
+	})
+	return runs, err
+}
+
+// LastTick returns the last time the cron schedule of the job fired, ok is false if it never fired.
+func (s *Store) LastTick(job string, schedule string) (last time.Time, ok bool, err error) {
+	if s == nil {
+		return time.Time{}, false, nil
+	}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(ticksBucket).Get(tickKey(job, schedule))
+		if value == nil {
+			return nil
+		}
+		ok = true
+		return last.UnmarshalText(value)
+	})
+	return last, ok, err
+}
+
+// RecordTick stores the time the cron schedule of the job fired.
+func (s *Store) RecordTick(job string, schedule string, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	value, err := at.MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(ticksBucket).Put(tickKey(job, schedule), value)
+	})
+}
+
+func tickKey(job string, schedule string) []byte {
+	return []byte(job + "\x00" + schedule)
+}
+
+func key(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// truncate keeps the tail of the output, where errors usually are.
+func truncate(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	start := len(output) - limit
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return truncatedMark + output[start:]
+}
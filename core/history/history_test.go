@@ -0,0 +1,124 @@
+package history_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/history"
+)
+
+func record(t *testing.T, store *history.Store, job string, status history.Status) {
+	t.Helper()
+	now := time.Now()
+	assert.NoError(t, store.Record(&history.JobRun{
+		Job:    job,
+		Event:  map[string]any{"emitter": "cron"},
+		Start:  now,
+		End:    now,
+		Status: status,
+		Tasks: []history.TaskRun{
+			{Name: "step_1", Status: status, Attempts: 1, Output: "ok"},
+		},
+	}))
+}
+
+func TestStore_RecordAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	store, err := history.Open(dir, 0, 0)
+	assert.NoError(t, err)
+	record(t, store, "backup", history.StatusSuccess)
+	record(t, store, "backup", history.StatusFailure)
+	record(t, store, "other", history.StatusSuccess)
+	assert.NoError(t, store.Close())
+
+	// runs survive restarts
+	store, err = history.Open(dir, 0, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	runs, err := store.Runs("backup", history.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(runs))
+	assert.Equal(t, uint64(2), runs[0].ID)
+	assert.Equal(t, history.StatusFailure, runs[0].Status)
+	assert.Equal(t, "cron", runs[0].Event["emitter"])
+	assert.Equal(t, "step_1", runs[0].Tasks[0].Name)
+
+	runs, err = store.Runs("backup", history.Query{Status: history.StatusSuccess, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(runs))
+	assert.Equal(t, uint64(1), runs[0].ID)
+
+	runs, err = store.Runs("missing", history.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(runs))
+}
+
+func TestStore_Retention(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 3, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+	for range 5 {
+		record(t, store, "backup", history.StatusSuccess)
+	}
+
+	runs, err := store.Runs("backup", history.Query{})
+	assert.NoError(t, err)
+	ids := []uint64{}
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	assert.Equal(t, []uint64{5, 4, 3}, ids)
+}
+
+func TestStore_TruncatesOutput(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0, 4)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.NoError(t, store.Record(&history.JobRun{
+		Job:   "backup",
+		Tasks: []history.TaskRun{{Name: "dump", Output: strings.Repeat("x", 10) + "fail"}},
+	}))
+
+	runs, err := store.Runs("backup", history.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[truncated] fail", runs[0].Tasks[0].Output)
+}
+
+func TestStore_Nil(t *testing.T) {
+	var store *history.Store
+	assert.NoError(t, store.Record(&history.JobRun{Job: "backup"}))
+	runs, err := store.Runs("backup", history.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(runs))
+	assert.NoError(t, store.Close())
+}
+
+func TestStore_Ticks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := history.Open(dir, 0, 0)
+	assert.NoError(t, err)
+
+	_, ok, err := store.LastTick("backup", "0 3 * * *")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	at := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	assert.NoError(t, store.RecordTick("backup", "0 3 * * *", at))
+	assert.NoError(t, store.Close())
+
+	store, err = history.Open(dir, 0, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+	last, ok, err := store.LastTick("backup", "0 3 * * *")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, at.Equal(last))
+
+	_, ok, err = store.LastTick("other", "0 3 * * *")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
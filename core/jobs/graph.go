@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/ctxutils"
+)
+
+// node is a single task of a job alongside its position in the dependency graph.
+type node struct {
+	name  string
+	task  abstraction.Executable
+	needs []int
+}
+
+// buildNodes pairs compiled tasks with their config and resolves `needs` into node indexes,
+// tasks are expected to be in the same order as job.Tasks and the graph to be validated beforehand.
+func buildNodes(job config.JobConfig, tasks []abstraction.Executable) []*node {
+	ids := make(map[string]int, len(job.Tasks))
+	for i, t := range job.Tasks {
+		if t.ID != "" {
+			ids[t.ID] = i
+		}
+	}
+	nodes := make([]*node, 0, len(tasks))
+	for i, task := range tasks {
+		cfg := job.Tasks[i]
+		n := &node{
+			name: cfg.ID,
+			task: task,
+		}
+		if n.name == "" {
+			n.name = fmt.Sprintf("step_%d", i+1)
+		}
+		for _, need := range cfg.Needs {
+			n.needs = append(n.needs, ids[need])
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// executeGraph runs every node as soon as all of its parents succeeded,
+// each running node holds its own slot of the lock so independent branches run in parallel.
+// Result of each node is exported into the variable table as `<id>_<field>` for its descendants.
+func executeGraph(
+	c context.Context,
+	logger *zap.Logger,
+	nodes []*node,
+	hooks *jobHooks,
+	lock sync.Locker,
+	summary *runSummary,
+) {
+	vars := summary.vars
+	summaryLock := new(sync.Mutex)
+	finished := make([]chan struct{}, len(nodes))
+	succeeded := make([]bool, len(nodes))
+	for i := range nodes {
+		finished[i] = make(chan struct{})
+	}
+	skip := func(n *node) {
+		summaryLock.Lock()
+		defer summaryLock.Unlock()
+		summary.skipNodes([]*node{n})
+	}
+
+	wg := new(sync.WaitGroup)
+	for i, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[i])
+			for _, parent := range n.needs {
+				<-finished[parent]
+				if !succeeded[parent] {
+					logger.Warn(
+						"skipping task because one of its dependencies did not succeed",
+						zap.String("task", n.name),
+						zap.String("dependency", nodes[parent].name),
+					)
+					skip(n)
+					return
+				}
+			}
+			summaryLock.Lock()
+			ctx := context.WithValue(c, ctxutils.Vars, maps.Clone(vars))
+			summaryLock.Unlock()
+
+			lock.Lock()
+			if ctx.Err() != nil {
+				lock.Unlock()
+				logger.Warn("skipping task because the run is canceled", zap.String("task", n.name), zap.Error(ctx.Err()))
+				skip(n)
+				return
+			}
+			res, err := runTask(ctx, n.task, hooks)
+			lock.Unlock()
+
+			summaryLock.Lock()
+			res.Export(n.name, vars)
+			summary.recordTask(n.name, res, err)
+			skipped := errors.Is(err, common.ErrSkipped)
+			switch {
+			case skipped:
+				summary.skipUnmet(n.name)
+			case err != nil:
+				summary.failed = append(summary.failed, n.name)
+			}
+			summaryLock.Unlock()
+			// tasks depending on a task skipped by its when condition still run
+			succeeded[i] = err == nil || skipped
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/history"
+	"github.com/fmotalleb/crontab-go/ctxutils"
+)
+
+// jobHooks holds compiled hooks of a job.
+type jobHooks struct {
+	// executed once per task
+	done   []abstraction.Executable
+	failed []abstraction.Executable
+
+	// executed once per run
+	start   []abstraction.Executable
+	success []abstraction.Executable
+	failure []abstraction.Executable
+	finally []abstraction.Executable
+}
+
+// runSummary is the aggregated outcome of a single run of a job.
+type runSummary struct {
+	start   time.Time
+	failed  []string
+	skipped []string
+	// unmet are the skipped tasks whose when condition did not hold, they do not fail the run
+	unmet []string
+	vars  map[string]string
+	tasks []history.TaskRun
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{
+		start: time.Now(),
+		vars:  map[string]string{},
+	}
+}
+
+func (s *runSummary) succeeded() bool {
+	return len(s.failed) == 0 && len(s.skipped) == len(s.unmet)
+}
+
+func (s *runSummary) skipUnmet(name string) {
+	s.skipped = append(s.skipped, name)
+	s.unmet = append(s.unmet, name)
+}
+
+func (s *runSummary) skipNodes(nodes []*node) {
+	now := time.Now()
+	for _, n := range nodes {
+		s.skipped = append(s.skipped, n.name)
+		s.tasks = append(s.tasks, history.TaskRun{Name: n.name, Start: now, End: now, Status: history.StatusSkipped})
+	}
+}
+
+// recordTask adds the outcome of a task to the run history record.
+func (s *runSummary) recordTask(name string, res *common.Result, err error) {
+	end := time.Now()
+	status, message := history.StatusSuccess, res.Error
+	switch {
+	case errors.Is(err, common.ErrSkipped):
+		status = history.StatusSkipped
+	case err != nil:
+		status = history.StatusFailure
+		if errors.Is(err, common.ErrTimedOut) {
+			status = history.StatusTimedOut
+		}
+		message = cmp.Or(message, err.Error())
+	}
+	s.tasks = append(s.tasks, history.TaskRun{
+		Name:       name,
+		Start:      end.Add(-res.Duration),
+		End:        end,
+		Status:     status,
+		ExitCode:   res.ExitCode,
+		StatusCode: res.StatusCode,
+		Attempts:   res.Attempt,
+		Output:     res.Output,
+		Error:      message,
+	})
+}
+
+// record stores the run into the run history (if enabled).
+func (s *runSummary) record(ctx context.Context, logger *zap.Logger, job string) {
+	status := history.StatusSuccess
+	if !s.succeeded() {
+		status = history.StatusFailure
+	}
+	run := &history.JobRun{
+		Job:    job,
+		Start:  s.start,
+		End:    time.Now(),
+		Status: status,
+		Tasks:  s.tasks,
+	}
+	if e, ok := ctx.Value(ctxutils.EventData).(abstraction.Event); ok {
+		run.Event = e.GetData()
+	}
+	if err := global.Get[*history.Store]().Record(run); err != nil {
+		logger.Warn("failed to record the run into history", zap.Error(err))
+	}
+}
+
+// export writes the summary into the variable table as `job_<field>`.
+func (s *runSummary) export(vars map[string]string) {
+	status := "success"
+	if !s.succeeded() {
+		status = "failure"
+	}
+	vars["job_status"] = status
+	vars["job_failed_tasks"] = strings.Join(s.failed, ",")
+	vars["job_skipped_tasks"] = strings.Join(s.skipped, ",")
+	vars["job_duration"] = time.Since(s.start).String()
+}
+
+// executeRunHooks executes hooks of a run one by one, hooks are executed even if the run is canceled.
+func executeRunHooks(ctx context.Context, logger *zap.Logger, name string, hooks []abstraction.Executable, vars map[string]string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		hookCtx := context.WithValue(ctx, ctxutils.Vars, maps.Clone(vars))
+		if err := hook.Execute(hookCtx); err != nil && !errors.Is(err, common.ErrSkipped) {
+			logger.Warn("run hook failed", zap.String("hook", name), zap.Error(err))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package jobs
 
 import (
 	"context"
+	"sync"
 
 	"go.uber.org/zap"
 
@@ -13,38 +14,58 @@ import (
 	"github.com/fmotalleb/crontab-go/ctxutils"
 )
 
-func initEventSignal(ed abstraction.EventDispatcher, events []abstraction.EventGenerator, logger *zap.Logger) {
+func initEventSignal(
+	ctx context.Context,
+	generators *sync.WaitGroup,
+	ed abstraction.EventDispatcher,
+	events []abstraction.EventGenerator,
+	logger *zap.Logger,
+) {
 	for _, ev := range events {
-		go ev.BuildTickChannel(ed)
+		generators.Add(1)
+		go func() {
+			defer generators.Done()
+			ev.BuildTickChannel(ctx, ed)
+		}()
 	}
 	logger.Debug("signals initialized")
 }
 
-func initTasks(job config.JobConfig, logger *zap.Logger) ([]abstraction.Executable, []abstraction.Executable, []abstraction.Executable) {
-	tasks := make([]abstraction.Executable, 0, len(job.Tasks))
-	doneHooks := make([]abstraction.Executable, 0, len(job.Hooks.Done))
-	failHooks := make([]abstraction.Executable, 0, len(job.Hooks.Failed))
-
+func initTasks(job config.JobConfig, logger *zap.Logger) ([]abstraction.Executable, *jobHooks) {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, ctxutils.JobKey, job.Name)
-	for _, t := range job.Tasks {
-		tasks = append(tasks, task.Build(ctx, logger, t))
+	build := func(cfg []config.Task) []abstraction.Executable {
+		result := make([]abstraction.Executable, 0, len(cfg))
+		for _, t := range cfg {
+			result = append(result, task.Build(ctx, logger, t))
+		}
+		return result
 	}
+
+	tasks := build(job.Tasks)
 	logger.Debug("Compiled Tasks")
-	for _, t := range job.Hooks.Done {
-		doneHooks = append(doneHooks, task.Build(ctx, logger, t))
-	}
-	logger.Debug("Compiled Hooks.Done")
-	for _, t := range job.Hooks.Failed {
-		failHooks = append(failHooks, task.Build(ctx, logger, t))
+	hooks := &jobHooks{
+		done:    build(job.Hooks.Done),
+		failed:  build(job.Hooks.Failed),
+		start:   build(job.Hooks.OnStart),
+		success: build(job.Hooks.OnSuccess),
+		failure: build(job.Hooks.OnFailure),
+		finally: build(job.Hooks.Finally),
 	}
-	logger.Debug("Compiled Hooks.Fail")
-	return tasks, doneHooks, failHooks
+	logger.Debug("Compiled Hooks")
+	return tasks, hooks
 }
 
 func initEvents(job config.JobConfig, logger *zap.Logger) []abstraction.EventGenerator {
 	events := make([]abstraction.EventGenerator, 0, len(job.Events))
 	for _, sh := range job.Events {
+		if sh.Cron != "" && job.CatchesUp() {
+			sh.CatchUp = &config.CatchUp{
+				Job:      job.Name,
+				Policy:   job.CatchUp,
+				Lookback: job.CatchUpLookback,
+			}
+		}
 		events = append(events, event.Build(logger, &sh))
 	}
 	return events
@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+// defaultMaxQueue leaves pending events of the queue policy unlimited unless max-queue is set.
+const defaultMaxQueue = -1
+
+const (
+	OverlapMetricName = "overlapped_events"
+	OverlapMetricHelp = "amount of events dropped because previous runs of the job were still in progress"
+)
+
+// runGuard limits the amount of simultaneous runs of a job (one run per event)
+// and decides what happens to an event when all slots are taken.
+// The guard of a changed job is carried over to its new config, so in-flight runs are still accounted for.
+type runGuard struct {
+	mu           sync.Mutex
+	policy       config.OverlapPolicy
+	maxQueue     int
+	concurrency  int
+	tasks        []abstraction.Executable
+	queued       uint
+	running      []*activeRun
+	freed        chan struct{}
+	log          *zap.Logger
+	metricLabels prometheus.Labels
+}
+
+type activeRun struct {
+	cancel context.CancelFunc
+	tasks  []abstraction.Executable
+}
+
+func newRunGuard(job *config.JobConfig, tasks []abstraction.Executable, logger *zap.Logger) *runGuard {
+	g := &runGuard{freed: make(chan struct{})}
+	g.update(job, tasks, logger)
+	return g
+}
+
+// update applies config of the job to the guard, runs that are in progress keep their slots.
+func (g *runGuard) update(job *config.JobConfig, tasks []abstraction.Executable, logger *zap.Logger) {
+	policy := job.Overlap
+	if policy == "" {
+		policy = config.OverlapQueue
+	}
+	metricLabels := prometheus.Labels{
+		"job":    job.Name,
+		"policy": string(policy),
+	}
+	global.RegisterCounter(
+		OverlapMetricName,
+		OverlapMetricHelp,
+		metricLabels,
+	)
+	maxQueue := job.MaxQueue
+	if maxQueue == 0 {
+		maxQueue = defaultMaxQueue
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policy = policy
+	g.maxQueue = maxQueue
+	g.concurrency = int(max(job.Concurrency, 1))
+	g.tasks = tasks
+	g.log = logger.With(zap.String("overlap", string(policy)))
+	g.metricLabels = metricLabels
+	// wake queued events up, the amount of slots may have grown
+	g.notify()
+}
+
+// acquire reserves a slot for a new run according to the overlap policy.
+// It returns the context of the run and a release function that must be called once the run is finished,
+// if the event must be dropped it returns false.
+func (g *runGuard) acquire(ctx context.Context) (context.Context, func(), bool) {
+	g.mu.Lock()
+	if len(g.running) < g.concurrency {
+		defer g.mu.Unlock()
+		return g.start(ctx)
+	}
+	switch g.policy {
+	case config.OverlapSkip:
+		g.reject("previous run is still in progress, skipping event")
+		g.mu.Unlock()
+		return nil, nil, false
+	case config.OverlapReplace:
+		g.cancelOldest()
+	default:
+		if !g.enqueue() {
+			g.reject("overlap queue is full, dropping event")
+			g.mu.Unlock()
+			return nil, nil, false
+		}
+		defer g.dequeue()
+	}
+	g.mu.Unlock()
+
+	for {
+		g.mu.Lock()
+		if len(g.running) < g.concurrency {
+			defer g.mu.Unlock()
+			return g.start(ctx)
+		}
+		freed := g.freed
+		g.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, nil, false
+		}
+	}
+}
+
+// start registers a new run, the lock must be held.
+func (g *runGuard) start(ctx context.Context) (context.Context, func(), bool) {
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &activeRun{cancel: cancel, tasks: g.tasks}
+	g.running = append(g.running, run)
+	release := func() {
+		cancel()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for i, r := range g.running {
+			if r == run {
+				g.running = append(g.running[:i], g.running[i+1:]...)
+				break
+			}
+		}
+		g.notify()
+	}
+	return runCtx, release, true
+}
+
+// notify wakes up events waiting for a slot, the lock must be held.
+func (g *runGuard) notify() {
+	close(g.freed)
+	g.freed = make(chan struct{})
+}
+
+// cancelOldest cancels context of the oldest run, cancellation is propagated to
+// every executable of that run since their contexts are derived from it.
+// Executables are canceled as well if the job has a single slot, otherwise they may belong to other runs.
+// The lock must be held.
+func (g *runGuard) cancelOldest() {
+	if len(g.running) == 0 {
+		return
+	}
+	g.log.Info("previous run is still in progress, replacing it")
+	oldest := g.running[0]
+	oldest.cancel()
+	if g.concurrency == 1 {
+		for _, task := range oldest.tasks {
+			task.Cancel()
+		}
+	}
+}
+
+// enqueue reserves a place in the queue, the lock must be held.
+func (g *runGuard) enqueue() bool {
+	if g.maxQueue >= 0 && g.queued >= uint(g.maxQueue) {
+		return false
+	}
+	g.queued++
+	return true
+}
+
+func (g *runGuard) dequeue() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queued--
+}
+
+// reject drops the event, the lock must be held.
+func (g *runGuard) reject(message string) {
+	g.log.Warn(message)
+	global.IncMetric(
+		OverlapMetricName,
+		OverlapMetricHelp,
+		g.metricLabels,
+	)
+}
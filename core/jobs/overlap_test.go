@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/task"
+)
+
+func newTestGuard(policy config.OverlapPolicy, maxQueue int) *runGuard {
+	return newRunGuard(
+		&config.JobConfig{
+			Name:        "overlap_" + string(policy),
+			Concurrency: 1,
+			Overlap:     policy,
+			MaxQueue:    maxQueue,
+		},
+		nil,
+		zap.NewNop(),
+	)
+}
+
+func TestRunGuard_Skip(t *testing.T) {
+	guard := newTestGuard(config.OverlapSkip, 0)
+	_, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+
+	_, _, ok = guard.acquire(t.Context())
+	assert.False(t, ok)
+
+	release()
+	_, release, ok = guard.acquire(t.Context())
+	assert.True(t, ok)
+	release()
+}
+
+func TestRunGuard_QueueDefaultLimit(t *testing.T) {
+	guard := newTestGuard("", 0)
+	assert.Equal(t, -1, guard.maxQueue)
+
+	guard = newTestGuard(config.OverlapQueue, -1)
+	_, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+	defer release()
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	for range 3 {
+		assert.True(t, guard.enqueue(), "queue without limit must accept events")
+	}
+}
+
+func TestRunGuard_QueueDefaultKeepsBurst(t *testing.T) {
+	guard := newTestGuard("", 0)
+	_, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+
+	const burst = 5
+	ran := make(chan bool, burst)
+	for range burst {
+		go func() {
+			_, r, ok := guard.acquire(t.Context())
+			if ok {
+				r()
+			}
+			ran <- ok
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); ; {
+		guard.mu.Lock()
+		queued := guard.queued
+		guard.mu.Unlock()
+		if queued == burst {
+			break
+		}
+		assert.True(t, time.Now().Before(deadline), "burst was not queued")
+		time.Sleep(time.Millisecond)
+	}
+
+	release()
+	for range burst {
+		assert.True(t, <-ran, "queued event was dropped")
+	}
+}
+
+func TestRunGuard_QueueLimit(t *testing.T) {
+	guard := newTestGuard(config.OverlapQueue, 1)
+	_, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+
+	queued := make(chan bool)
+	go func() {
+		_, r, ok := guard.acquire(t.Context())
+		if ok {
+			r()
+		}
+		queued <- ok
+	}()
+	for deadline := time.Now().Add(time.Second); ; {
+		guard.mu.Lock()
+		queuedCount := guard.queued
+		guard.mu.Unlock()
+		if queuedCount == 1 {
+			break
+		}
+		assert.True(t, time.Now().Before(deadline), "event was never queued")
+		time.Sleep(time.Millisecond)
+	}
+
+	_, _, ok = guard.acquire(t.Context())
+	assert.False(t, ok, "queue is full, event must be dropped")
+
+	release()
+	assert.True(t, <-queued)
+}
+
+func TestRunGuard_Replace(t *testing.T) {
+	guard := newTestGuard(config.OverlapReplace, 0)
+	first, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+	go func() {
+		<-first.Done()
+		release()
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	second, release, ok := guard.acquire(ctx)
+	assert.True(t, ok)
+	assert.Error(t, first.Err())
+	assert.NoError(t, second.Err())
+	release()
+}
+
+func TestRunGuard_ReplaceStopsCommand(t *testing.T) {
+	cmd := task.Build(t.Context(), zap.NewNop(), config.Task{Command: "sleep 30", RetryDelay: time.Millisecond})
+	guard := newRunGuard(
+		&config.JobConfig{Name: "overlap_replace_command", Concurrency: 1, Overlap: config.OverlapReplace},
+		[]abstraction.Executable{cmd},
+		zap.NewNop(),
+	)
+	first, release, ok := guard.acquire(t.Context())
+	assert.True(t, ok)
+	exited := make(chan error, 1)
+	go func() {
+		ctx, _ := common.WithResult(first)
+		exited <- cmd.Execute(ctx)
+		release()
+	}()
+	// let the command start
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	_, release, ok = guard.acquire(ctx)
+	assert.True(t, ok)
+	defer release()
+	select {
+	case err := <-exited:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("replaced command did not exit")
+	}
+}
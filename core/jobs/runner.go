@@ -1,6 +1,13 @@
 package jobs
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/maniartech/signals"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -11,56 +18,314 @@ import (
 	"github.com/fmotalleb/crontab-go/config"
 	"github.com/fmotalleb/crontab-go/core/concurrency"
 	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/process"
 )
 
-func InitializeJobs(jobs []*config.JobConfig) {
-	log := global.Logger("Cron")
+// generatorsStopTimeout limits how long stopping a job waits for its event generators.
+const generatorsStopTimeout = 10 * time.Second
+
+// Manager owns the running jobs, jobs can be replaced at runtime using Apply.
+type Manager struct {
+	ctx        context.Context
+	runsCtx    context.Context
+	cancelRuns context.CancelFunc
+	runs       *runTracker
+	log        *zap.Logger
+	mu         sync.Mutex
+	// jobs holds running jobs by their source (e.g. the config file or discovery)
+	jobs map[string]map[string]*runningJob
+	// closed is set by Shutdown, no jobs are started afterwards
+	closed bool
+}
+
+// ErrManagerClosed is returned by Manager.ApplySource once the manager is shut down.
+var ErrManagerClosed = errors.New("job manager is shut down")
+
+// runningJob is a started job, its event generators run until it is stopped.
+type runningJob struct {
+	name        string
+	fingerprint string
+	cancel      context.CancelFunc
+	generators  *sync.WaitGroup
+	guard       *runGuard
+}
+
+// Changes lists names of the jobs affected by Manager.Apply.
+type Changes struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// NewManager creates a manager, event generators of its jobs are stopped once ctx is done
+// while their runs are stopped by Shutdown.
+func NewManager(ctx context.Context) *Manager {
+	runsCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	return &Manager{
+		ctx:        ctx,
+		runsCtx:    runsCtx,
+		cancelRuns: cancelRuns,
+		runs:       &runTracker{},
+		log:        global.Logger("Cron"),
+		jobs:       make(map[string]map[string]*runningJob),
+	}
+}
+
+// InitializeJobs starts the given jobs using a new manager.
+func InitializeJobs(jobs []*config.JobConfig) *Manager {
+	m := NewManager(global.CTX())
+	// a new manager is not closed, so applying cannot fail
+	_, _ = m.Apply(jobs)
+	return m
+}
+
+// ConfigSource is the source of jobs defined in the config file.
+const ConfigSource = "config"
+
+// Apply diffs the given jobs against the running jobs of the config file, see ApplySource.
+func (m *Manager) Apply(jobs []*config.JobConfig) (Changes, error) {
+	return m.ApplySource(ConfigSource, jobs)
+}
+
+// ApplySource diffs the given jobs against the running jobs of the same source by name
+// (or by their whole config if they are not named): removed and changed jobs are stopped,
+// added and changed jobs are started and the rest keep running untouched. Jobs of other sources are not affected.
+// Stopping a job stops its event generators, its in-flight runs are allowed to finish.
+// In-flight runs of a changed job keep their slots, so its overlap policy holds across the change.
+// Jobs are expected to be validated beforehand. ErrManagerClosed is returned once Shutdown is called.
+func (m *Manager) ApplySource(source string, jobs []*config.JobConfig) (Changes, error) {
+	var stopped []*runningJob
+	defer func() {
+		// generators are waited for outside the lock, so other sources are not blocked meanwhile
+		m.awaitStopped(stopped)
+	}()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Changes{}, ErrManagerClosed
+	}
+	running := m.jobs[source]
+	if running == nil {
+		running = make(map[string]*runningJob)
+		m.jobs[source] = running
+	}
+	changes := Changes{}
+	desired := make(map[string]*config.JobConfig, len(jobs))
+	fingerprints := make(map[string]string, len(jobs))
 	for _, job := range jobs {
 		if job.Disabled {
-			log.Warn("job is disabled", zap.String("job.name", job.Name))
+			m.log.Warn("job is disabled", zap.String("job.name", job.Name))
 			continue
 		}
-		// Setting default value of concurrency
-		if job.Concurrency == 0 {
-			job.Concurrency = 1
+		fp := fingerprint(job)
+		key := job.Name
+		if _, duplicate := desired[key]; key == "" || duplicate {
+			key = fmt.Sprintf("%s#%s", job.Name, fp)
 		}
+		desired[key] = job
+		fingerprints[key] = fp
+	}
 
-		lock, err := concurrency.NewConcurrentPool(job.Concurrency)
-		if err != nil {
-			log.Panic("failed to validate job", zap.String("job.name", job.Name), zap.Error(err))
+	for key, current := range running {
+		job, ok := desired[key]
+		switch {
+		case !ok:
+			changes.Removed = append(changes.Removed, current.name)
+		case fingerprints[key] != current.fingerprint:
+			changes.Changed = append(changes.Changed, current.name)
+		default:
+			changes.Unchanged = append(changes.Unchanged, current.name)
+			continue
 		}
-		logger := log.With(
-			zap.String("job.name", job.Name),
-			zap.Uint("job.concurrency", job.Concurrency),
-		)
-		if err := job.Validate(logger.Named("Validator")); err != nil {
-			log.Panic("failed to validate job", zap.String("job", job.Name), zap.Error(err))
+		current.cancel()
+		stopped = append(stopped, current)
+		delete(running, key)
+		if ok {
+			running[key] = m.start(job, fingerprints[key], current.guard)
 		}
-		var signal abstraction.EventDispatcher = signals.NewSync[abstraction.Event]()
-		if job.Debounce > 0 {
-			signal = debouncer.NewDebouncedSignal(signal, job.Debounce)
+	}
+	for key, job := range desired {
+		if _, ok := running[key]; ok {
+			continue
 		}
-		global.CountSignals(signal,
-			"events",
-			"amount of events dispatched for this job",
-			prometheus.Labels{
-				"job": job.Name,
-			},
-		)
-		tasks, doneHooks, failHooks := initTasks(*job, logger.Named("Task"))
-		logger.Debug("Tasks initialized")
+		changes.Added = append(changes.Added, job.Name)
+		running[key] = m.start(job, fingerprints[key], nil)
+	}
+	m.log.Info(
+		"Jobs Are Ready",
+		zap.String("source", source),
+		zap.Strings("added", changes.Added),
+		zap.Strings("removed", changes.Removed),
+		zap.Strings("changed", changes.Changed),
+		zap.Int("unchanged", len(changes.Unchanged)),
+	)
+	return changes, nil
+}
 
-		taskHandler(logger.Named("TaskRunner"), signal, tasks, doneHooks, failHooks, lock)
-		buildSignal(signal, *job, logger.Named("SignalGen"))
+// fingerprint identifies the configuration of the job, any change in the config results in a new fingerprint.
+func fingerprint(job *config.JobConfig) string {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Sprintf("%p", job)
+	}
+	return string(encoded)
+}
+
+// start starts the job, runs of the job are guarded by guard if given (the guard of the job it replaces).
+func (m *Manager) start(job *config.JobConfig, fp string, guard *runGuard) *runningJob {
+	log := m.log
+	// Setting default value of concurrency
+	if job.Concurrency == 0 {
+		job.Concurrency = 1
+	}
+
+	lock, err := concurrency.NewConcurrentPool(job.Concurrency)
+	if err != nil {
+		log.Panic("failed to validate job", zap.String("job.name", job.Name), zap.Error(err))
+	}
+	logger := log.With(
+		zap.String("job.name", job.Name),
+		zap.Uint("job.concurrency", job.Concurrency),
+	)
+	if err := job.Validate(logger.Named("Validator")); err != nil {
+		log.Panic("failed to validate job", zap.String("job", job.Name), zap.Error(err))
+	}
+	var signal abstraction.EventDispatcher = signals.NewSync[abstraction.Event]()
+	if job.Debounce > 0 {
+		signal = debouncer.NewDebouncedSignal(signal, job.Debounce)
+	}
+	global.CountSignals(signal,
+		"events",
+		"amount of events dispatched for this job",
+		prometheus.Labels{
+			"job": job.Name,
+		},
+	)
+	tasks, hooks := initTasks(*job, logger.Named("Task"))
+	logger.Debug("Tasks initialized")
+
+	nodes := buildNodes(*job, tasks)
+	if guard == nil {
+		guard = newRunGuard(job, tasks, logger.Named("Overlap"))
+	} else {
+		guard.update(job, tasks, logger.Named("Overlap"))
+	}
+	taskHandler(m.runsCtx, m.runs, logger.Named("TaskRunner"), job.Name, signal, job.Mode, nodes, hooks, lock, guard)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	generators := new(sync.WaitGroup)
+	buildSignal(ctx, generators, signal, *job, logger.Named("SignalGen"))
+
+	logger.Debug("EventLoop initialized")
+	return &runningJob{
+		name:        job.Name,
+		fingerprint: fp,
+		cancel:      cancel,
+		generators:  generators,
+		guard:       guard,
+	}
+}
+
+// awaitStopped waits for event generators of the stopped jobs to return, jobs must be canceled beforehand.
+// All jobs share a single timeout since they are stopping simultaneously.
+func (m *Manager) awaitStopped(jobs []*runningJob) {
+	deadline := time.Now().Add(generatorsStopTimeout)
+	for _, job := range jobs {
+		log := m.log.With(zap.String("job.name", job.name))
+		stopped := make(chan struct{})
+		go func() {
+			job.generators.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			log.Info("job stopped")
+		case <-time.After(time.Until(deadline)):
+			log.Warn("event generators of the job did not stop in time", zap.Duration("timeout", generatorsStopTimeout))
+		}
+	}
+}
+
+// Shutdown stops all jobs: no new events are accepted, in-flight runs are given the grace period to finish,
+// afterwards they are canceled (their commands receive the stop signal, and are killed if they do not exit in time).
+// The manager is closed before waiting, so jobs applied meanwhile (e.g. by a reload) are rejected instead of blocking.
+func (m *Manager) Shutdown(grace time.Duration) {
+	m.mu.Lock()
+	m.closed = true
+	var stopped []*runningJob
+	for source, running := range m.jobs {
+		for _, job := range running {
+			job.cancel()
+			stopped = append(stopped, job)
+		}
+		delete(m.jobs, source)
+	}
+	m.mu.Unlock()
+	m.awaitStopped(stopped)
+	log := m.log.With(zap.Duration("grace", grace))
+	log.Info("waiting for running jobs to finish")
+	if m.runs.drain(grace) {
+		log.Info("all running jobs finished")
+		m.cancelRuns()
+		return
+	}
+	log.Warn("running jobs did not finish in the grace period, stopping them")
+	m.cancelRuns()
+	if !m.runs.drain(process.KillDelay + time.Second) {
+		log.Warn("running jobs did not stop in time")
+	}
+}
+
+// runTracker keeps track of in-flight runs, no runs are accepted once it is drained.
+type runTracker struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	drained bool
+}
+
+// add registers a run, it returns false if the tracker is drained.
+func (r *runTracker) add() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.drained {
+		return false
+	}
+	r.wg.Add(1)
+	return true
+}
+
+func (r *runTracker) done() {
+	r.wg.Done()
+}
 
-		logger.Debug("EventLoop initialized")
+// drain stops accepting runs and waits for the in-flight ones, it returns false if they did not finish in time.
+func (r *runTracker) drain(timeout time.Duration) bool {
+	r.mu.Lock()
+	r.drained = true
+	r.mu.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
-	log.Info("Jobs Are Ready")
 }
 
-func buildSignal(ed abstraction.EventDispatcher, job config.JobConfig, logger *zap.Logger) {
+func buildSignal(
+	ctx context.Context,
+	generators *sync.WaitGroup,
+	ed abstraction.EventDispatcher,
+	job config.JobConfig,
+	logger *zap.Logger,
+) {
 	events := initEvents(job, logger)
 	logger.Debug("Events initialized")
 
-	initEventSignal(ed, events, logger)
+	initEventSignal(ctx, generators, ed, events, logger)
 }
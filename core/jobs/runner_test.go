@@ -0,0 +1,287 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/maniartech/signals"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/concurrency"
+	"github.com/fmotalleb/crontab-go/core/event"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+type blockingTask struct {
+	common.Cancelable
+	common.Hooked
+
+	started  chan struct{}
+	release  chan struct{}
+	finished chan error
+}
+
+func (b *blockingTask) Execute(ctx context.Context) error {
+	close(b.started)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	b.finished <- ctx.Err()
+	return nil
+}
+
+func webJob(name string, event string, command string) *config.JobConfig {
+	return &config.JobConfig{
+		Name:   name,
+		Events: []config.JobEvent{{WebEvent: event}},
+		Tasks:  []config.Task{{Command: command}},
+	}
+}
+
+func mustApply(t *testing.T, m *Manager, source string, jobs []*config.JobConfig) Changes {
+	t.Helper()
+	changes, err := m.ApplySource(source, jobs)
+	assert.NoError(t, err)
+	return changes
+}
+
+func waitListeners(t *testing.T, event string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(global.CTX().EventListeners()[event]) != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d listeners of %s, found %d", count, event, len(global.CTX().EventListeners()[event]))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManager_Apply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+
+	changes := mustApply(t, m, ConfigSource, []*config.JobConfig{
+		webJob("keep", "manager-keep", "true"),
+		webJob("change", "manager-change", "true"),
+		webJob("remove", "manager-remove", "true"),
+	})
+	slices.Sort(changes.Added)
+	assert.Equal(t, []string{"change", "keep", "remove"}, changes.Added)
+	waitListeners(t, "manager-keep", 1)
+	waitListeners(t, "manager-change", 1)
+	waitListeners(t, "manager-remove", 1)
+	kept := m.jobs[ConfigSource]["keep"]
+
+	changes = mustApply(t, m, ConfigSource, []*config.JobConfig{
+		webJob("keep", "manager-keep", "true"),
+		webJob("change", "manager-changed", "true"),
+		webJob("add", "manager-add", "true"),
+	})
+	assert.Equal(t, Changes{
+		Added:     []string{"add"},
+		Removed:   []string{"remove"},
+		Changed:   []string{"change"},
+		Unchanged: []string{"keep"},
+	}, changes)
+
+	// generators of removed and changed jobs are stopped, unchanged jobs are untouched
+	waitListeners(t, "manager-remove", 0)
+	waitListeners(t, "manager-change", 0)
+	waitListeners(t, "manager-changed", 1)
+	waitListeners(t, "manager-add", 1)
+	waitListeners(t, "manager-keep", 1)
+	assert.True(t, kept == m.jobs[ConfigSource]["keep"])
+}
+
+func TestManager_ApplyKeepsRunsOfChangedJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+
+	job := webJob("overlap", "manager-overlap", "true")
+	job.Overlap = config.OverlapSkip
+	mustApply(t, m, ConfigSource, []*config.JobConfig{job})
+	guard := m.jobs[ConfigSource]["overlap"].guard
+	_, release, ok := guard.acquire(ctx)
+	assert.True(t, ok)
+
+	changed := webJob("overlap", "manager-overlap-changed", "true")
+	changed.Overlap = config.OverlapSkip
+	mustApply(t, m, ConfigSource, []*config.JobConfig{changed})
+	assert.True(t, guard == m.jobs[ConfigSource]["overlap"].guard)
+	_, _, ok = guard.acquire(ctx)
+	assert.False(t, ok, "run of the previous config is still in progress")
+
+	release()
+	_, release, ok = guard.acquire(ctx)
+	assert.True(t, ok)
+	release()
+}
+
+func TestManager_ApplySkipsDisabledJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+
+	mustApply(t, m, ConfigSource, []*config.JobConfig{webJob("disabled", "manager-disabled", "true")})
+	waitListeners(t, "manager-disabled", 1)
+
+	job := webJob("disabled", "manager-disabled", "true")
+	job.Disabled = true
+	changes := mustApply(t, m, ConfigSource, []*config.JobConfig{job})
+	assert.Equal(t, []string{"disabled"}, changes.Removed)
+	waitListeners(t, "manager-disabled", 0)
+}
+
+func TestManager_UnnamedJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+
+	jobs := []*config.JobConfig{
+		webJob("", "manager-unnamed", "true"),
+		webJob("", "manager-unnamed", "false"),
+	}
+	changes := mustApply(t, m, ConfigSource, jobs)
+	assert.Equal(t, 2, len(changes.Added))
+	waitListeners(t, "manager-unnamed", 2)
+
+	changes = mustApply(t, m, ConfigSource, []*config.JobConfig{
+		webJob("", "manager-unnamed", "true"),
+		webJob("", "manager-unnamed", "false"),
+	})
+	assert.Equal(t, 2, len(changes.Unchanged))
+	assert.Equal(t, 0, len(changes.Added))
+}
+
+func TestManager_ApplySource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+
+	mustApply(t, m, ConfigSource, []*config.JobConfig{webJob("backup", "manager-config", "true")})
+	changes := mustApply(t, m, "discovery", []*config.JobConfig{webJob("backup", "manager-discovered", "true")})
+	assert.Equal(t, []string{"backup"}, changes.Added)
+	waitListeners(t, "manager-config", 1)
+	waitListeners(t, "manager-discovered", 1)
+
+	// jobs of other sources are not removed
+	changes = mustApply(t, m, ConfigSource, nil)
+	assert.Equal(t, []string{"backup"}, changes.Removed)
+	waitListeners(t, "manager-config", 0)
+	waitListeners(t, "manager-discovered", 1)
+
+	mustApply(t, m, "discovery", nil)
+	waitListeners(t, "manager-discovered", 0)
+}
+
+func newBlockingTask() *blockingTask {
+	return &blockingTask{
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+		finished: make(chan error, 1),
+	}
+}
+
+func blockingHandler(t *testing.T, base context.Context, runs *runTracker, task *blockingTask) abstraction.EventDispatcher {
+	t.Helper()
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+	ed := signals.NewSync[abstraction.Event]()
+	taskHandler(
+		base,
+		runs,
+		zap.NewNop(),
+		"blocking",
+		ed,
+		config.JobModeSequential,
+		buildNodes(config.JobConfig{Tasks: []config.Task{{}}}, []abstraction.Executable{task}),
+		&jobHooks{},
+		lock,
+		newTestGuard(config.OverlapQueue, 0),
+	)
+	return ed
+}
+
+func TestTaskHandler_RunsOutliveGenerators(t *testing.T) {
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	task := newBlockingTask()
+	ed := blockingHandler(t, base, &runTracker{}, task)
+
+	generator, stopGenerator := context.WithCancel(context.Background())
+	ed.Emit(generator, event.NewMetaData("test", nil))
+	<-task.started
+	stopGenerator()
+	close(task.release)
+	assert.NoError(t, <-task.finished)
+}
+
+func TestManager_ShutdownWaitsForRuns(t *testing.T) {
+	m := NewManager(context.Background())
+	task := newBlockingTask()
+	ed := blockingHandler(t, m.runsCtx, m.runs, task)
+
+	ed.Emit(context.Background(), event.NewMetaData("test", nil))
+	<-task.started
+	time.AfterFunc(50*time.Millisecond, func() { close(task.release) })
+	m.Shutdown(time.Minute)
+	assert.NoError(t, <-task.finished)
+
+	// events are ignored after shutdown
+	ed.Emit(context.Background(), event.NewMetaData("test", nil))
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-task.finished:
+		t.Fatal("run started after shutdown")
+	default:
+	}
+}
+
+func TestManager_ShutdownCancelsRunsAfterGrace(t *testing.T) {
+	m := NewManager(context.Background())
+	task := newBlockingTask()
+	ed := blockingHandler(t, m.runsCtx, m.runs, task)
+
+	ed.Emit(context.Background(), event.NewMetaData("test", nil))
+	<-task.started
+	m.Shutdown(10 * time.Millisecond)
+	assert.IsError(t, <-task.finished, context.Canceled)
+}
+
+func TestManager_ApplyAfterShutdown(t *testing.T) {
+	m := NewManager(context.Background())
+	mustApply(t, m, ConfigSource, []*config.JobConfig{webJob("closing", "manager-closing", "true")})
+	waitListeners(t, "manager-closing", 1)
+	task := newBlockingTask()
+	ed := blockingHandler(t, m.runsCtx, m.runs, task)
+
+	ed.Emit(context.Background(), event.NewMetaData("test", nil))
+	<-task.started
+	shutdown := make(chan struct{})
+	go func() {
+		m.Shutdown(time.Minute)
+		close(shutdown)
+	}()
+	// generators are stopped once the manager is closed
+	waitListeners(t, "manager-closing", 0)
+
+	// applying does not wait for the in-flight run held by shutdown
+	_, err := m.Apply([]*config.JobConfig{webJob("late", "manager-late", "true")})
+	assert.IsError(t, err, ErrManagerClosed)
+	_, err = m.ApplySource("discovery", nil)
+	assert.IsError(t, err, ErrManagerClosed)
+	assert.Equal(t, 0, len(global.CTX().EventListeners()["manager-late"]))
+
+	close(task.release)
+	<-shutdown
+	assert.NoError(t, <-task.finished)
+}
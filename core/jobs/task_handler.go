@@ -2,51 +2,145 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"go.uber.org/zap"
 
 	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
 	"github.com/fmotalleb/crontab-go/ctxutils"
 )
 
 func taskHandler(
+	base context.Context,
+	runs *runTracker,
 	logger *zap.Logger,
+	job string,
 	ed abstraction.EventDispatcher,
-	tasks []abstraction.Executable,
-	doneHooks []abstraction.Executable,
-	failHooks []abstraction.Executable,
+	mode config.JobMode,
+	nodes []*node,
+	hooks *jobHooks,
 	lock sync.Locker,
+	guard *runGuard,
 ) {
-	logger.Debug("Spawning task handler")
+	logger.Debug("Spawning task handler", zap.String("mode", string(mode)))
 	ed.AddListener(func(ctx context.Context, e abstraction.Event) {
 		logger.Debug("Signal Received")
-		for _, task := range tasks {
-			ctxInternal := context.WithValue(ctx, ctxutils.EventData, e)
-			go executeTask(ctxInternal, task, doneHooks, failHooks, lock)
+		if !runs.add() {
+			logger.Debug("event ignored, shutting down")
+			return
 		}
+		// runs outlive the event generator that triggered them (e.g. when the job is reloaded),
+		// they are only canceled alongside the base context
+		ctxInternal, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		ctxInternal = context.WithValue(ctxInternal, ctxutils.EventData, e)
+		stop := context.AfterFunc(base, cancel)
+		go func() {
+			defer runs.done()
+			defer cancel()
+			defer stop()
+			runCtx, release, ok := guard.acquire(ctxInternal)
+			if !ok {
+				return
+			}
+			defer release()
+			executeRun(runCtx, logger, job, mode, nodes, hooks, lock)
+		}()
 	})
 }
 
-func executeTask(
+// executeRun executes a single run of the job (triggered by one event) alongside its run hooks
+// and records it into the run history.
+func executeRun(
+	ctx context.Context,
+	logger *zap.Logger,
+	job string,
+	mode config.JobMode,
+	nodes []*node,
+	hooks *jobHooks,
+	lock sync.Locker,
+) {
+	summary := newRunSummary()
+	executeRunHooks(ctx, logger, "on-start", hooks.start, summary.vars)
+	switch mode {
+	case config.JobModeSequential:
+		executePipeline(ctx, logger, nodes, hooks, lock, summary)
+	default:
+		executeGraph(ctx, logger, nodes, hooks, lock, summary)
+	}
+	summary.export(summary.vars)
+	summary.record(ctx, logger, job)
+	if summary.succeeded() {
+		executeRunHooks(ctx, logger, "on-success", hooks.success, summary.vars)
+	} else {
+		logger.Warn(
+			"run finished with failures",
+			zap.Strings("failed", summary.failed),
+			zap.Strings("skipped", summary.skipped),
+		)
+		executeRunHooks(ctx, logger, "on-failure", hooks.failure, summary.vars)
+	}
+	executeRunHooks(ctx, logger, "finally", hooks.finally, summary.vars)
+}
+
+// executePipeline runs tasks in order using a single slot of the lock,
+// result of each step is exported into the variable table as `<id>_<field>` (or `step_<n>_<field>`)
+// so later steps can use it, first failing step stops the pipeline.
+func executePipeline(
 	c context.Context,
-	task abstraction.Executable,
-	doneHooks []abstraction.Executable,
-	failHooks []abstraction.Executable,
+	logger *zap.Logger,
+	nodes []*node,
+	hooks *jobHooks,
 	lock sync.Locker,
+	summary *runSummary,
 ) {
 	lock.Lock()
 	defer lock.Unlock()
+	vars := summary.vars
+	ctx := context.WithValue(c, ctxutils.Vars, vars)
+	for i, n := range nodes {
+		if ctx.Err() != nil {
+			logger.Warn("pipeline canceled", zap.String("step", n.name), zap.Error(ctx.Err()))
+			summary.skipNodes(nodes[i:])
+			return
+		}
+		res, err := runTask(ctx, n.task, hooks)
+		res.Export(n.name, vars)
+		summary.recordTask(n.name, res, err)
+		if errors.Is(err, common.ErrSkipped) {
+			summary.skipUnmet(n.name)
+			continue
+		}
+		if err != nil {
+			logger.Warn("pipeline stopped due to a failed step", zap.String("step", n.name), zap.Error(err))
+			summary.failed = append(summary.failed, n.name)
+			summary.skipNodes(nodes[i+1:])
+			return
+		}
+	}
+}
+
+func runTask(
+	c context.Context,
+	task abstraction.Executable,
+	hooks *jobHooks,
+) (*common.Result, error) {
 	ctx := context.WithValue(c, ctxutils.TaskKey, task)
-	err := task.Execute(ctx)
-	switch err {
-	case nil:
-		for _, task := range doneHooks {
+	taskCtx, res := common.WithResult(ctx)
+	err := task.Execute(taskCtx)
+	ctx = common.WithTriggerResult(ctx, res)
+	switch {
+	case errors.Is(err, common.ErrSkipped):
+	case err == nil:
+		for _, task := range hooks.done {
 			_ = task.Execute(ctx)
 		}
 	default:
-		for _, task := range failHooks {
+		for _, task := range hooks.failed {
 			_ = task.Execute(ctx)
 		}
 	}
+	return res, err
 }
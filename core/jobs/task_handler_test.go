@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/concurrency"
+	"github.com/fmotalleb/crontab-go/core/event"
+	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/history"
+	"github.com/fmotalleb/crontab-go/core/task"
+	"github.com/fmotalleb/crontab-go/ctxutils"
+)
+
+type mockTask struct {
+	common.Cancelable
+	common.Hooked
+
+	output string
+	stdout string
+	err    error
+	seen   map[string]string
+	called int
+}
+
+func (m *mockTask) Execute(ctx context.Context) error {
+	m.called++
+	if vars, ok := ctx.Value(ctxutils.Vars).(map[string]string); ok {
+		m.seen = make(map[string]string, len(vars))
+		for k, v := range vars {
+			m.seen[k] = v
+		}
+	}
+	common.ResultOf(ctx).AppendOutput([]byte(m.output))
+	common.ResultOf(ctx).AppendStreams([]byte(m.stdout), nil)
+	return m.err
+}
+
+func TestExecutePipeline_PassesOutput(t *testing.T) {
+	first := &mockTask{output: "dump.sql\nwarning: slow disk", stdout: "dump.sql"}
+	second := &mockTask{output: "dump.sql.gz"}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{{}, {}}},
+		[]abstraction.Executable{first, second},
+	)
+	executePipeline(t.Context(), zap.NewNop(), nodes, &jobHooks{}, lock, newRunSummary())
+
+	assert.Equal(t, 1, second.called)
+	assert.Equal(t, "dump.sql\nwarning: slow disk", second.seen["step_1_output"])
+	assert.Equal(t, "dump.sql", second.seen["step_1_stdout"])
+	assert.Equal(t, "0", second.seen["step_1_exit_code"])
+}
+
+func TestExecutePipeline_TaskVarsDoNotLeak(t *testing.T) {
+	first := task.Build(t.Context(), zap.NewNop(), config.Task{
+		Command:    "true",
+		Vars:       map[string]string{"x": "a"},
+		RetryDelay: time.Millisecond,
+	})
+	second := &mockTask{}
+	finally := &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{{}, {}}},
+		[]abstraction.Executable{first, second},
+	)
+	executeRun(t.Context(), zap.NewNop(), "", config.JobModeSequential, nodes, &jobHooks{finally: []abstraction.Executable{finally}}, lock)
+
+	assert.Equal(t, 1, second.called)
+	_, leaked := second.seen["x"]
+	assert.False(t, leaked)
+	assert.Equal(t, "0", second.seen["step_1_exit_code"])
+	_, leaked = finally.seen["x"]
+	assert.False(t, leaked)
+}
+
+func TestExecutePipeline_StopsOnFailure(t *testing.T) {
+	first := &mockTask{err: errors.New("failed")}
+	second := &mockTask{}
+	failHook := &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{{}, {}}},
+		[]abstraction.Executable{first, second},
+	)
+	summary := newRunSummary()
+	executePipeline(
+		t.Context(),
+		zap.NewNop(),
+		nodes,
+		&jobHooks{failed: []abstraction.Executable{failHook}},
+		lock,
+		summary,
+	)
+
+	assert.Equal(t, 1, first.called)
+	assert.Equal(t, 0, second.called)
+	assert.Equal(t, 1, failHook.called)
+	assert.Equal(t, []string{"step_1"}, summary.failed)
+	assert.Equal(t, []string{"step_2"}, summary.skipped)
+}
+
+func TestExecuteGraph_JoinsBranches(t *testing.T) {
+	users := &mockTask{output: "users.sql"}
+	orders := &mockTask{output: "orders.sql"}
+	manifest := &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(2)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{
+			{ID: "users"},
+			{ID: "orders"},
+			{ID: "manifest", Needs: []string{"users", "orders"}},
+		}},
+		[]abstraction.Executable{users, orders, manifest},
+	)
+	executeGraph(t.Context(), zap.NewNop(), nodes, &jobHooks{}, lock, newRunSummary())
+
+	assert.Equal(t, 1, manifest.called)
+	assert.Equal(t, "users.sql", manifest.seen["users_output"])
+	assert.Equal(t, "orders.sql", manifest.seen["orders_output"])
+}
+
+func TestExecuteGraph_SkipsDescendantsOfFailedTask(t *testing.T) {
+	dump := &mockTask{err: errors.New("failed")}
+	other := &mockTask{}
+	upload := &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{
+			{ID: "dump"},
+			{ID: "other"},
+			{ID: "upload", Needs: []string{"dump"}},
+		}},
+		[]abstraction.Executable{dump, other, upload},
+	)
+	summary := newRunSummary()
+	executeGraph(t.Context(), zap.NewNop(), nodes, &jobHooks{}, lock, summary)
+
+	assert.Equal(t, 1, dump.called)
+	assert.Equal(t, 1, other.called)
+	assert.Equal(t, 0, upload.called)
+	assert.Equal(t, []string{"dump"}, summary.failed)
+	assert.Equal(t, []string{"upload"}, summary.skipped)
+}
+
+func TestExecuteRun_HooksOncePerRun(t *testing.T) {
+	tasks := []abstraction.Executable{&mockTask{}, &mockTask{err: errors.New("failed")}, &mockTask{}}
+	start, success, failure, finally := &mockTask{}, &mockTask{}, &mockTask{}, &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(3)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(config.JobConfig{Tasks: []config.Task{{}, {}, {}}}, tasks)
+	hooks := &jobHooks{
+		start:   []abstraction.Executable{start},
+		success: []abstraction.Executable{success},
+		failure: []abstraction.Executable{failure},
+		finally: []abstraction.Executable{finally},
+	}
+	executeRun(t.Context(), zap.NewNop(), "test", config.JobModeParallel, nodes, hooks, lock)
+
+	assert.Equal(t, 1, start.called)
+	assert.Equal(t, 0, success.called)
+	assert.Equal(t, 1, failure.called)
+	assert.Equal(t, 1, finally.called)
+	assert.Equal(t, "failure", finally.seen["job_status"])
+	assert.Equal(t, "step_2", finally.seen["job_failed_tasks"])
+}
+
+func TestExecutePipeline_ContinuesAfterUnmetCondition(t *testing.T) {
+	first := &mockTask{err: common.ErrSkipped}
+	second := &mockTask{}
+	doneHook, failHook := &mockTask{}, &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{{}, {}}},
+		[]abstraction.Executable{first, second},
+	)
+	summary := newRunSummary()
+	executePipeline(
+		t.Context(),
+		zap.NewNop(),
+		nodes,
+		&jobHooks{done: []abstraction.Executable{doneHook}, failed: []abstraction.Executable{failHook}},
+		lock,
+		summary,
+	)
+
+	assert.Equal(t, 1, second.called)
+	assert.Equal(t, 1, doneHook.called)
+	assert.Equal(t, 0, failHook.called)
+	assert.Equal(t, []string{"step_1"}, summary.skipped)
+	assert.Zero(t, summary.failed)
+	assert.True(t, summary.succeeded())
+}
+
+func TestExecuteGraph_RunsDescendantsOfUnmetTask(t *testing.T) {
+	notify := &mockTask{err: common.ErrSkipped}
+	cleanup := &mockTask{}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+
+	nodes := buildNodes(
+		config.JobConfig{Tasks: []config.Task{
+			{ID: "notify"},
+			{ID: "cleanup", Needs: []string{"notify"}},
+		}},
+		[]abstraction.Executable{notify, cleanup},
+	)
+	summary := newRunSummary()
+	executeGraph(t.Context(), zap.NewNop(), nodes, &jobHooks{}, lock, summary)
+
+	assert.Equal(t, 1, cleanup.called)
+	assert.Equal(t, []string{"notify"}, summary.skipped)
+	assert.True(t, summary.succeeded())
+}
+
+func TestExecuteRun_RecordsHistory(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0, 0)
+	assert.NoError(t, err)
+	global.Put(store)
+	t.Cleanup(func() {
+		global.Put[*history.Store](nil)
+		assert.NoError(t, store.Close())
+	})
+	tasks := []abstraction.Executable{&mockTask{output: "dumped"}, &mockTask{err: errors.New("failed")}, &mockTask{}}
+	lock, err := concurrency.NewConcurrentPool(1)
+	assert.NoError(t, err)
+	nodes := buildNodes(config.JobConfig{Tasks: []config.Task{{ID: "dump"}, {ID: "upload"}, {ID: "notify"}}}, tasks)
+
+	ctx := context.WithValue(t.Context(), ctxutils.EventData, event.NewMetaData("cron", nil))
+	executeRun(ctx, zap.NewNop(), "backup", config.JobModeSequential, nodes, &jobHooks{}, lock)
+
+	runs, err := store.Runs("backup", history.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(runs))
+	run := runs[0]
+	assert.Equal(t, history.StatusFailure, run.Status)
+	assert.Equal(t, "cron", run.Event["emitter"])
+	statuses := map[string]history.Status{}
+	for _, task := range run.Tasks {
+		statuses[task.Name] = task.Status
+	}
+	assert.Equal(t, map[string]history.Status{
+		"dump":   history.StatusSuccess,
+		"upload": history.StatusFailure,
+		"notify": history.StatusSkipped,
+	}, statuses)
+	assert.Equal(t, "dumped", run.Tasks[0].Output)
+	assert.Equal(t, "failed", run.Tasks[1].Error)
+}
+
+func TestRunSummary_RecordsTimedOutTask(t *testing.T) {
+	summary := newRunSummary()
+	summary.recordTask("dump", &common.Result{}, fmt.Errorf("%w: signal: terminated", common.ErrTimedOut))
+	summary.recordTask("upload", &common.Result{}, errors.New("failed"))
+	assert.Equal(t, history.StatusTimedOut, summary.tasks[0].Status)
+	assert.Equal(t, "timed out: signal: terminated", summary.tasks[0].Error)
+	assert.Equal(t, history.StatusFailure, summary.tasks[1].Status)
+}
@@ -0,0 +1,197 @@
+// Package logfields parses structured log records (json, logfmt) and evaluates conditions on their fields.
+package logfields
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Formats lists supported structured formats.
+var Formats = []string{FormatJSON, FormatLogfmt}
+
+// Parse parses a single record in the given format into its fields.
+func Parse(format string, record string) (map[string]any, error) {
+	switch format {
+	case FormatJSON:
+		fields := make(map[string]any)
+		if err := json.Unmarshal([]byte(record), &fields); err != nil {
+			return nil, fmt.Errorf("record is not a json object: %w", err)
+		}
+		return fields, nil
+	case FormatLogfmt:
+		return parseLogfmt(record)
+	default:
+		return nil, fmt.Errorf("unknown log format `%s`", format)
+	}
+}
+
+// parseLogfmt parses `key=value key="quoted value" flag` records, bare keys receive an empty value.
+func parseLogfmt(record string) (map[string]any, error) {
+	fields := make(map[string]any)
+	rest := strings.TrimSpace(record)
+	for rest != "" {
+		end := strings.IndexFunc(rest, func(r rune) bool { return r == '=' || unicode.IsSpace(r) })
+		if end == 0 {
+			return nil, fmt.Errorf("invalid logfmt record, unexpected `%c`", rest[0])
+		}
+		if end < 0 {
+			end = len(rest)
+		}
+		key := rest[:end]
+		rest = rest[end:]
+		value := ""
+		if strings.HasPrefix(rest, "=") {
+			rest = rest[1:]
+			var err error
+			if value, rest, err = logfmtValue(rest); err != nil {
+				return nil, fmt.Errorf("invalid logfmt value of `%s`: %w", key, err)
+			}
+		}
+		fields[key] = value
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return fields, nil
+}
+
+func logfmtValue(src string) (string, string, error) {
+	if !strings.HasPrefix(src, `"`) {
+		end := strings.IndexFunc(src, unicode.IsSpace)
+		if end < 0 {
+			return src, "", nil
+		}
+		return src[:end], src[end:], nil
+	}
+	escaped := false
+	for i := 1; i < len(src); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case src[i] == '\\':
+			escaped = true
+		case src[i] == '"':
+			value, err := strconv.Unquote(src[:i+1])
+			return value, src[i+1:], err
+		}
+	}
+	return "", "", errors.New("unterminated quoted value")
+}
+
+// Conditions is a list of compiled conditions, all of them must hold for a record to match.
+type Conditions []condition
+
+type condition struct {
+	path  []string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+var operators = []string{"==", "!=", "=~", "!~"}
+
+// Compile compiles conditions in form of `<field> <op> <value>` where op is one of `==`, `!=`, `=~` (regex) or `!~`,
+// nested fields of json records can be reached using dots (`http.status == 500`).
+func Compile(exprs []string) (Conditions, error) {
+	conditions := make(Conditions, 0, len(exprs))
+	for _, expr := range exprs {
+		c, err := compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func compile(expr string) (condition, error) {
+	idx, op := -1, ""
+	for _, candidate := range operators {
+		if i := strings.Index(expr, candidate); i > 0 && (idx < 0 || i < idx) {
+			idx, op = i, candidate
+		}
+	}
+	if idx < 0 {
+		return condition{}, fmt.Errorf("invalid condition `%s`, expected `<field> <op> <value>` with op one of %v", expr, operators)
+	}
+	field := strings.TrimSpace(expr[:idx])
+	if field == "" {
+		return condition{}, fmt.Errorf("invalid condition `%s`, missing field", expr)
+	}
+	value := strings.TrimSpace(expr[idx+len(op):])
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	c := condition{path: strings.Split(field, "."), op: op, value: value}
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return condition{}, fmt.Errorf("invalid regex in condition `%s`: %w", expr, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// Match reports whether fields satisfy every condition, missing fields only satisfy negative operators.
+func (cs Conditions) Match(fields map[string]any) bool {
+	for _, c := range cs {
+		if !c.match(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) match(fields map[string]any) bool {
+	value, ok := lookup(fields, c.path)
+	switch c.op {
+	case "==":
+		return ok && value == c.value
+	case "!=":
+		return !ok || value != c.value
+	case "=~":
+		return ok && c.re.MatchString(value)
+	default:
+		return !ok || !c.re.MatchString(value)
+	}
+}
+
+func lookup(fields map[string]any, path []string) (string, bool) {
+	if value, ok := fields[strings.Join(path, ".")]; ok {
+		return stringify(value), true
+	}
+	var current any = fields
+	for _, key := range path {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if current, ok = obj[key]; !ok {
+			return "", false
+		}
+	}
+	return stringify(current), true
+}
+
+func stringify(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]any, []any:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
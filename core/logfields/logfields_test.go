@@ -0,0 +1,82 @@
+package logfields_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/logfields"
+)
+
+func TestParse_JSON(t *testing.T) {
+	fields, err := logfields.Parse(logfields.FormatJSON, `{"level":"error","http":{"status":500}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"level": "error",
+		"http":  map[string]any{"status": float64(500)},
+	}, fields)
+
+	_, err = logfields.Parse(logfields.FormatJSON, `level=error`)
+	assert.Error(t, err)
+}
+
+func TestParse_Logfmt(t *testing.T) {
+	fields, err := logfields.Parse(logfields.FormatLogfmt, `level=error msg="request \"failed\"" service=api-gw retry`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"level":   "error",
+		"msg":     `request "failed"`,
+		"service": "api-gw",
+		"retry":   "",
+	}, fields)
+
+	_, err = logfields.Parse(logfields.FormatLogfmt, `msg="unterminated`)
+	assert.Error(t, err)
+}
+
+func TestConditions_Match(t *testing.T) {
+	conditions, err := logfields.Compile([]string{
+		`level == "error"`,
+		`service =~ "api.*"`,
+		`http.status != 200`,
+		`user !~ ^bot-`,
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, conditions.Match(map[string]any{
+		"level":   "error",
+		"service": "api-gw",
+		"http":    map[string]any{"status": float64(500)},
+	}))
+	assert.False(t, conditions.Match(map[string]any{
+		"level":   "info",
+		"service": "api-gw",
+	}))
+	assert.False(t, conditions.Match(map[string]any{
+		"level":   "error",
+		"service": "worker",
+	}))
+	assert.False(t, conditions.Match(map[string]any{
+		"level":   "error",
+		"service": "api",
+		"user":    "bot-1",
+	}))
+	assert.False(t, conditions.Match(map[string]any{
+		"level":   "error",
+		"service": "api",
+		"http":    map[string]any{"status": float64(200)},
+	}))
+}
+
+func TestConditions_DottedKey(t *testing.T) {
+	conditions, err := logfields.Compile([]string{`http.status == 500`})
+	assert.NoError(t, err)
+	assert.True(t, conditions.Match(map[string]any{"http.status": "500"}))
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expr := range []string{`level`, `== "error"`, `service =~ "("`} {
+		_, err := logfields.Compile([]string{expr})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package process manages process groups of commands executed by tasks and signals sent to them.
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// KillDelay is the default time given to a process group to exit after the stop signal, before it is killed.
+const KillDelay = 5 * time.Second
+
+// groupPollInterval is the interval of checking whether a process group exited.
+const groupPollInterval = 50 * time.Millisecond
+
+// DefaultStopSignal is sent to process groups of commands when they are stopped.
+const DefaultStopSignal = syscall.SIGTERM
+
+// StopSignal is the signal sent to process groups of commands when they are stopped (e.g. on shutdown),
+// the zero value falls back to DefaultStopSignal.
+type StopSignal syscall.Signal
+
+// Signal returns the stop signal, or the default one if it is not set.
+func (s StopSignal) Signal() syscall.Signal {
+	if s == 0 {
+		return DefaultStopSignal
+	}
+	return syscall.Signal(s)
+}
+
+// ParseSignal parses signal names (`SIGTERM`, `TERM` or `term`) and numbers (`15`).
+func ParseSignal(name string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(name); err == nil {
+		if num <= 0 {
+			return 0, fmt.Errorf("invalid signal number: %d", num)
+		}
+		return syscall.Signal(num), nil
+	}
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	if sig, ok := signals[upper]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal: %s", name)
+}
+
+// KillGroup waits for the process group led by the process to exit until the deadline, then kills what is left of it.
+func KillGroup(proc *os.Process, deadline time.Time) error {
+	for groupAlive(proc) && time.Now().Before(deadline) {
+		time.Sleep(groupPollInterval)
+	}
+	return SignalGroup(proc, syscall.SIGKILL)
+}
@@ -0,0 +1,27 @@
+package process_test
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/process"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGTERM", "TERM", "term", "15"} {
+		sig, err := process.ParseSignal(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, syscall.SIGTERM, sig, name)
+	}
+	_, err := process.ParseSignal("SIGNOPE")
+	assert.Error(t, err)
+	_, err = process.ParseSignal("-1")
+	assert.Error(t, err)
+}
+
+func TestStopSignal_Default(t *testing.T) {
+	assert.Equal(t, process.DefaultStopSignal, process.StopSignal(0).Signal())
+	assert.Equal(t, syscall.SIGINT, process.StopSignal(syscall.SIGINT).Signal())
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// SetGroup makes the command the leader of a new process group, so the command and its children
+// can be signaled at once and signals sent to the foreground group (e.g. ctrl+c) do not reach them.
+func SetGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// SignalGroup sends the signal to the process group led by the process, a group that is already gone is ignored.
+func SignalGroup(proc *os.Process, sig syscall.Signal) error {
+	if proc == nil {
+		return nil
+	}
+	err := syscall.Kill(-proc.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// groupAlive reports whether any process of the group led by the process exists.
+func groupAlive(proc *os.Process) bool {
+	return proc != nil && syscall.Kill(-proc.Pid, 0) == nil
+}
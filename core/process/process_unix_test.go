@@ -0,0 +1,33 @@
+//go:build unix
+
+package process_test
+
+import (
+	"io"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/process"
+)
+
+func TestSignalGroup_ReachesChildren(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30 & wait")
+	// children inherit the output pipe, wait returns only after all of them exit
+	cmd.Stdout = io.Discard
+	process.SetGroup(cmd)
+	assert.NoError(t, cmd.Start())
+
+	// give the shell a moment to spawn its children
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, process.SignalGroup(cmd.Process, syscall.SIGTERM))
+	assert.Error(t, cmd.Wait())
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// signaling a group that is already gone is not an error
+	assert.NoError(t, process.SignalGroup(cmd.Process, syscall.SIGKILL))
+}
@@ -0,0 +1,38 @@
+//go:build windows
+// +build windows
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var signals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// SetGroup NOOP, process groups are not supported on windows.
+func SetGroup(_ *exec.Cmd) {
+}
+
+// SignalGroup kills the process, windows processes cannot receive other signals.
+func SignalGroup(proc *os.Process, _ syscall.Signal) error {
+	if proc == nil {
+		return nil
+	}
+	err := proc.Kill()
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+	return err
+}
+
+// groupAlive is always false, process groups are not supported on windows.
+func groupAlive(_ *os.Process) bool {
+	return false
+}
@@ -0,0 +1,113 @@
+//go:build linux
+
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// reapInterval is the fallback interval of reaping, SIGCHLD signals may be coalesced.
+	reapInterval = 5 * time.Second
+
+	prSetChildSubreaper = 36
+)
+
+// Reap reaps orphaned children (zombies) until ctx is done, on SIGCHLD and periodically.
+// If the application is not PID 1 it becomes a child subreaper, so orphans of its commands are reparented to it.
+func Reap(ctx context.Context, log *zap.Logger) {
+	if os.Getpid() != 1 {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+			log.Warn("cannot become a child subreaper, orphans of commands will not be reaped", zap.Error(errno))
+		}
+	}
+	sigChld := make(chan os.Signal, 1)
+	signal.Notify(sigChld, syscall.SIGCHLD)
+	defer signal.Stop(sigChld)
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	log.Info("reaping orphaned processes")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChld:
+		case <-ticker.C:
+		}
+		for _, pid := range reap() {
+			log.Debug("reaped orphaned process", zap.Int("pid", pid))
+		}
+	}
+}
+
+// reap waits for exited children that are not commands tracked by Start, it returns their pids.
+// While no commands are tracked any child is waited using wait4(-1),
+// otherwise only zombie children found in /proc are waited (by pid).
+func reap() []int {
+	commands.Lock()
+	defer commands.Unlock()
+	reaped := []int{}
+	if len(commands.pids) == 0 {
+		for {
+			var status syscall.WaitStatus
+			pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil || pid <= 0 {
+				return reaped
+			}
+			reaped = append(reaped, pid)
+		}
+	}
+	for _, pid := range zombieChildren() {
+		if tracked(pid) {
+			continue
+		}
+		var status syscall.WaitStatus
+		if wpid, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil); err == nil && wpid == pid {
+			reaped = append(reaped, pid)
+		}
+	}
+	return reaped
+}
+
+// zombieChildren lists pids of children of this process that exited but are not waited yet.
+func zombieChildren() []int {
+	self := os.Getpid()
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	zombies := []int{}
+	for _, stat := range stats {
+		content, err := os.ReadFile(stat)
+		if err != nil {
+			continue
+		}
+		// pid (comm) state ppid ..., comm may contain spaces and parentheses
+		line := string(content)
+		end := strings.LastIndexByte(line, ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(line[end+1:])
+		if len(fields) < 2 || fields[0] != "Z" {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err != nil || ppid != self {
+			continue
+		}
+		if pid, err := strconv.Atoi(strings.Fields(line)[0]); err == nil {
+			zombies = append(zombies, pid)
+		}
+	}
+	return zombies
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"errors"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// orphanScript starts a short living child in background, prints its pid and exits with the given code.
+func orphanScript(code int) *exec.Cmd {
+	return exec.Command("sh", "-c", "sleep 0.1 >/dev/null & echo $!; exit "+strconv.Itoa(code))
+}
+
+func becomeSubreaper(t *testing.T) {
+	t.Helper()
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		t.Skipf("cannot become a child subreaper: %v", errno)
+	}
+}
+
+func waitZombie(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(zombieChildren(), pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d did not become a zombie child", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReap_Orphans(t *testing.T) {
+	becomeSubreaper(t)
+	// the shell exits right away, its child is reparented to the test process and exits afterwards
+	out, err := orphanScript(0).Output()
+	assert.NoError(t, err)
+	orphan, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	assert.NoError(t, err)
+	waitZombie(t, orphan)
+
+	assert.True(t, slices.Contains(reap(), orphan))
+	assert.False(t, slices.Contains(zombieChildren(), orphan))
+}
+
+func TestReap_SkipsTrackedCommands(t *testing.T) {
+	becomeSubreaper(t)
+	cmd := orphanScript(3)
+	stdout, err := cmd.StdoutPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, Start(cmd))
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NoError(t, err)
+	orphan, err := strconv.Atoi(strings.TrimSpace(line))
+	assert.NoError(t, err)
+	// both the tracked command and its orphan exit
+	waitZombie(t, cmd.Process.Pid)
+	waitZombie(t, orphan)
+
+	reaped := reap()
+	assert.True(t, slices.Contains(reaped, orphan))
+	assert.False(t, slices.Contains(reaped, cmd.Process.Pid))
+
+	err = Wait(cmd)
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, exitErr.ExitCode())
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package process
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Reap NOOP, reaping orphans is only supported on linux.
+func Reap(_ context.Context, log *zap.Logger) {
+	log.Warn("reaping orphaned processes is only supported on linux")
+}
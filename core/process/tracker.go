@@ -0,0 +1,38 @@
+package process
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// commands holds pids of commands started by Start that are not waited yet, the reaper never waits for them.
+var commands = struct {
+	sync.Mutex
+	pids map[int]struct{}
+}{pids: make(map[int]struct{})}
+
+// Start starts the command and tracks it until Wait, so the reaper does not race exec.Cmd.Wait.
+func Start(cmd *exec.Cmd) error {
+	commands.Lock()
+	defer commands.Unlock()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	commands.pids[cmd.Process.Pid] = struct{}{}
+	return nil
+}
+
+// Wait waits for the command started by Start and stops tracking it.
+func Wait(cmd *exec.Cmd) error {
+	defer func() {
+		commands.Lock()
+		defer commands.Unlock()
+		delete(commands.pids, cmd.Process.Pid)
+	}()
+	return cmd.Wait()
+}
+
+func tracked(pid int) bool {
+	_, ok := commands.pids[pid]
+	return ok
+}
@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fmotalleb/go-tools/log"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/helpers"
+)
+
+// defaultTokenLifetime is used for OAuth2 tokens issued without `expires_in`.
+const defaultTokenLifetime = time.Hour
+
+// tokenExpirySkew is subtracted from lifetime of OAuth2 tokens so they are refreshed before expiring mid-request.
+const tokenExpirySkew = 10 * time.Second
+
+type cachedToken struct {
+	value  string
+	expiry time.Time
+}
+
+// tokenEntry guards the token of a single client, so fetching it does not block other clients.
+type tokenEntry struct {
+	sync.Mutex
+	token *cachedToken
+}
+
+var (
+	tokensLock sync.Mutex
+	tokens     = make(map[string]*tokenEntry)
+)
+
+// authorize adds credentials of the auth config to the request, OAuth2 tokens are fetched using the given transport.
+func authorize(ctx context.Context, transport http.RoundTripper, auth *config.HTTPAuth, req *http.Request) error {
+	switch {
+	case auth == nil:
+		return nil
+	case auth.Basic != nil:
+		password := auth.Basic.Password
+		if auth.Basic.PasswordFile != "" {
+			var err error
+			if password, err = readSecret(auth.Basic.PasswordFile); err != nil {
+				return err
+			}
+		}
+		req.SetBasicAuth(auth.Basic.Username, password)
+	case auth.Bearer != nil:
+		token, err := readSecret(auth.Bearer.TokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case auth.OAuth2 != nil:
+		token, err := oauth2Token(ctx, transport, auth.OAuth2)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// oauth2Token returns the cached token of the client or fetches a new one using the client-credentials grant.
+// The lock of the client is held during the fetch so concurrent runs do not request multiple tokens.
+func oauth2Token(ctx context.Context, transport http.RoundTripper, cfg *config.OAuth2Auth) (string, error) {
+	entry := tokenEntryOf(cfg)
+	entry.Lock()
+	defer entry.Unlock()
+	if entry.token != nil && time.Now().Before(entry.token.expiry) {
+		return entry.token.value, nil
+	}
+	token, err := fetchOAuth2Token(ctx, transport, cfg)
+	if err != nil {
+		return "", err
+	}
+	entry.token = token
+	return token.value, nil
+}
+
+// forgetAuthorization drops the cached OAuth2 token used by the request (e.g. once it is rejected),
+// so the next run fetches a new one. Tokens cached after the request was authorized are kept.
+func forgetAuthorization(auth *config.HTTPAuth, req *http.Request) {
+	if auth == nil || auth.OAuth2 == nil {
+		return
+	}
+	entry := tokenEntryOf(auth.OAuth2)
+	entry.Lock()
+	defer entry.Unlock()
+	if entry.token != nil && req.Header.Get("Authorization") == "Bearer "+entry.token.value {
+		entry.token = nil
+	}
+}
+
+// tokenEntryOf returns the entry of the client, clients are identified by a hash of the whole config
+// (including params and the source of the secret) so clients differing in any field do not share tokens.
+func tokenEntryOf(cfg *config.OAuth2Auth) *tokenEntry {
+	// encoding/json sorts map keys, so params are hashed in a stable order
+	encoded, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(encoded)
+	key := string(sum[:])
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+	entry, ok := tokens[key]
+	if !ok {
+		entry = new(tokenEntry)
+		tokens[key] = entry
+	}
+	return entry
+}
+
+func fetchOAuth2Token(ctx context.Context, transport http.RoundTripper, cfg *config.OAuth2Auth) (*cachedToken, error) {
+	secret := cfg.ClientSecret
+	if cfg.ClientSecretFile != "" {
+		var err error
+		if secret, err = readSecret(cfg.ClientSecretFile); err != nil {
+			return nil, err
+		}
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(cfg.Scopes) != 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	for key, val := range cfg.Params {
+		form.Set(key, val)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(secret))
+
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch oauth2 token: %w", err)
+	}
+	defer helpers.WarnOnErrIgnored(log.Of(ctx), res.Body.Close, "cannot close token response body")
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch oauth2 token, token endpoint responded with status %d", res.StatusCode)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("cannot decode oauth2 token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("oauth2 token response does not contain an access_token")
+	}
+	token := &cachedToken{value: body.AccessToken, expiry: time.Now().Add(defaultTokenLifetime)}
+	if body.ExpiresIn > 0 {
+		token.expiry = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - tokenExpirySkew)
+	}
+	return token, nil
+}
@@ -0,0 +1,184 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	issued := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "crontab" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d-%s", n, r.FormValue("scope")),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, issued
+}
+
+func authorizedHeader(t *testing.T, auth *config.HTTPAuth) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+	assert.NoError(t, authorize(t.Context(), http.DefaultTransport, auth, req))
+	return req.Header.Get("Authorization")
+}
+
+func TestAuthorize_Basic(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0o600))
+
+	req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+	auth := &config.HTTPAuth{Basic: &config.BasicAuth{Username: "admin", PasswordFile: passwordFile}}
+	assert.NoError(t, authorize(t.Context(), http.DefaultTransport, auth, req))
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, "from-file", password)
+}
+
+func TestAuthorize_BearerFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0o600))
+	auth := &config.HTTPAuth{Bearer: &config.BearerAuth{TokenFile: tokenFile}}
+	assert.Equal(t, "Bearer first", authorizedHeader(t, auth))
+
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("rotated"), 0o600))
+	assert.Equal(t, "Bearer rotated", authorizedHeader(t, auth))
+}
+
+func TestAuthorize_OAuth2Cached(t *testing.T) {
+	server, issued := newTokenServer(t, 3600)
+	auth := &config.HTTPAuth{OAuth2: &config.OAuth2Auth{
+		TokenURL:     server.URL,
+		ClientID:     "crontab",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"read", "write"},
+	}}
+	assert.Equal(t, "Bearer token-1-read write", authorizedHeader(t, auth))
+	assert.Equal(t, "Bearer token-1-read write", authorizedHeader(t, auth))
+	assert.Equal(t, int32(1), issued.Load())
+}
+
+func TestAuthorize_OAuth2Refresh(t *testing.T) {
+	server, issued := newTokenServer(t, 1)
+	auth := &config.HTTPAuth{OAuth2: &config.OAuth2Auth{
+		TokenURL:     server.URL,
+		ClientID:     "crontab",
+		ClientSecret: "s3cret",
+	}}
+	assert.Equal(t, "Bearer token-1-", authorizedHeader(t, auth))
+	assert.Equal(t, "Bearer token-2-", authorizedHeader(t, auth))
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestAuthorize_OAuth2Rejected(t *testing.T) {
+	server, _ := newTokenServer(t, 3600)
+	req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+	auth := &config.HTTPAuth{OAuth2: &config.OAuth2Auth{
+		TokenURL:     server.URL,
+		ClientID:     "crontab",
+		ClientSecret: "wrong",
+	}}
+	err := authorize(t.Context(), http.DefaultTransport, auth, req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+}
+
+func TestAuthorize_OAuth2ForgetRejected(t *testing.T) {
+	server, issued := newTokenServer(t, 3600)
+	auth := &config.HTTPAuth{OAuth2: &config.OAuth2Auth{
+		TokenURL:     server.URL,
+		ClientID:     "crontab",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"forget"},
+	}}
+	stale := httptest.NewRequest(http.MethodGet, "http://test", nil)
+	stale.Header.Set("Authorization", "Bearer stale")
+	req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+	assert.NoError(t, authorize(t.Context(), http.DefaultTransport, auth, req))
+
+	forgetAuthorization(auth, stale)
+	assert.Equal(t, "Bearer token-1-forget", authorizedHeader(t, auth))
+
+	forgetAuthorization(auth, req)
+	assert.Equal(t, "Bearer token-2-forget", authorizedHeader(t, auth))
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestAuthorize_OAuth2FetchDoesNotBlockOtherClients(t *testing.T) {
+	fast, _ := newTokenServer(t, 3600)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+		_ = authorize(t.Context(), http.DefaultTransport, &config.HTTPAuth{OAuth2: &config.OAuth2Auth{TokenURL: slow.URL}}, req)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan string, 1)
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "http://test", nil)
+		_ = authorize(t.Context(), http.DefaultTransport, &config.HTTPAuth{OAuth2: &config.OAuth2Auth{
+			TokenURL:     fast.URL,
+			ClientID:     "crontab",
+			ClientSecret: "s3cret",
+		}}, req)
+		done <- req.Header.Get("Authorization")
+	}()
+	select {
+	case header := <-done:
+		assert.Equal(t, "Bearer token-1-", header)
+	case <-time.After(2 * time.Second):
+		t.Fatal("token fetch of another client blocked the fetch")
+	}
+}
+
+func TestTokenEntryOf_IdentifiesWholeConfig(t *testing.T) {
+	base := config.OAuth2Auth{
+		TokenURL: "http://token",
+		ClientID: "crontab",
+		Scopes:   []string{"read"},
+		Params:   map[string]string{"audience": "a", "resource": "r"},
+	}
+	same := base
+	same.Params = map[string]string{"resource": "r", "audience": "a"}
+	assert.True(t, tokenEntryOf(&base) == tokenEntryOf(&same))
+
+	otherParams := base
+	otherParams.Params = map[string]string{"audience": "b", "resource": "r"}
+	assert.True(t, tokenEntryOf(&base) != tokenEntryOf(&otherParams))
+
+	otherSecret := base
+	otherSecret.ClientSecret = "s3cret"
+	assert.True(t, tokenEntryOf(&base) != tokenEntryOf(&otherSecret))
+
+	otherSecretFile := base
+	otherSecretFile.ClientSecretFile = "/run/secrets/client"
+	assert.True(t, tokenEntryOf(&base) != tokenEntryOf(&otherSecretFile))
+}
@@ -50,8 +50,9 @@ type Command struct {
 }
 
 // Execute implements common.RetryHooked.
-func (c Command) Do(ctx context.Context) (e error) {
+func (c *Command) Do(ctx context.Context) (e error) {
 	ctx = populateVars(ctx, c.task)
+	result := common.ResultOf(ctx)
 	log := c.log.With(
 		zap.Time("start", time.Now()),
 	)
@@ -97,7 +98,10 @@ func (c Command) Do(ctx context.Context) (e error) {
 			return errors.Join(errors.New("failed to connect"), err)
 		}
 		ans, err := connection.Execute()
+		result.AppendOutput(ans)
+		err = common.TimeoutError(cmdCtx, err)
 		if err != nil {
+			result.SetError(err)
 			l.Error("failed to run command", zap.Error(err))
 			return errors.Join(errors.New("failed to execute command"), err)
 		}
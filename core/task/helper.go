@@ -3,6 +3,8 @@ package task
 import (
 	"bytes"
 	"context"
+	"io"
+	"maps"
 	"net/http"
 
 	"github.com/fmotalleb/go-tools/log"
@@ -10,6 +12,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/expect"
 	"github.com/fmotalleb/crontab-go/ctxutils"
 )
 
@@ -19,13 +24,82 @@ func logHTTPResponse(r *http.Response) (string, error) {
 	return result.String(), err
 }
 
+// captureHTTPResponse stores status and body of the response in the result and returns the whole body,
+// the body is replaced with an in-memory copy so it can still be read afterwards.
+// Only the head of bodies exceeding the output limit is stored in the result, marked with common.TruncatedMark.
+func captureHTTPResponse(result *common.Result, r *http.Response) ([]byte, error) {
+	result.StatusCode = r.StatusCode
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	result.Response = string(body)
+	if limit := common.OutputLimit(); len(body) > limit {
+		result.Response = common.TruncatedMark + string(body[:limit])
+	}
+	return body, err
+}
+
+// renderHeaders returns a copy of headers with every value rendered using the template data of the context.
+func renderHeaders(ctx context.Context, logger *zap.Logger, headers map[string]string) map[string]string {
+	rendered := make(map[string]string, len(headers))
+	for key, val := range headers {
+		rendered[key] = command.Render(ctx, logger, val)
+	}
+	return rendered
+}
+
+// renderData returns a copy of data with every string leaf rendered using the template data of the context.
+func renderData(ctx context.Context, logger *zap.Logger, data any) any {
+	switch d := data.(type) {
+	case string:
+		return command.Render(ctx, logger, d)
+	case map[string]any:
+		rendered := make(map[string]any, len(d))
+		for key, val := range d {
+			rendered[key] = renderData(ctx, logger, val)
+		}
+		return rendered
+	case map[any]any:
+		rendered := make(map[any]any, len(d))
+		for key, val := range d {
+			rendered[key] = renderData(ctx, logger, val)
+		}
+		return rendered
+	case []any:
+		rendered := make([]any, len(d))
+		for i, val := range d {
+			rendered[i] = renderData(ctx, logger, val)
+		}
+		return rendered
+	default:
+		return data
+	}
+}
+
+// compileExpect compiles the `expect` rules of the task, without rules any status below 400 is accepted.
+// Invalid rules are rejected by the config validation beforehand.
+func compileExpect(logger *zap.Logger, task *config.Task) *expect.Rules {
+	cfg := task.Expect
+	if cfg == nil {
+		cfg = &config.HTTPExpect{}
+	}
+	rules, err := expect.New(cfg.Status, cfg.Body, cfg.JSON)
+	if err != nil {
+		logger.Panic("invalid expect rules", zap.Error(err))
+	}
+	return rules
+}
+
 func populateVars(ctx context.Context, task *config.Task) context.Context {
 	var ok bool
 	var old map[string]string
 	if old, ok = ctx.Value(ctxutils.Vars).(map[string]string); !ok {
 		old = make(map[string]string, 0)
 	}
-	varTable := old
+	// vars of the task are only visible to the task itself, the shared table of the run is not modified
+	varTable := maps.Clone(old)
 	for k, v := range task.Vars {
 		var err error
 		varTable[k], err = template.EvaluateTemplate(v, varTable)
@@ -0,0 +1,222 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/expect"
+	"github.com/fmotalleb/crontab-go/helpers"
+)
+
+const defaultMaxRedirects = 10
+
+func init() {
+	tg.Register(NewHTTP)
+}
+
+func NewHTTP(logger *zap.Logger, task *config.Task) (abstraction.Executable, bool) {
+	if task.HTTP == nil {
+		return nil, false
+	}
+	method := strings.ToUpper(task.HTTP.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	h := newHTTP(logger, task, method, task.HTTP)
+	h.SetMetaName(fmt.Sprintf("http: %s %s", method, task.HTTP.URL))
+	return h, true
+}
+
+// newHTTP builds an http task sending request with the given method, used by get and post tasks as well.
+func newHTTP(logger *zap.Logger, task *config.Task, method string, request *config.HTTPRequest) *HTTP {
+	h := &HTTP{
+		method:  method,
+		request: request,
+		headers: &task.Headers,
+		data:    &task.Data,
+		task:    task,
+		expect:  compileExpect(logger, task),
+		log: logger.With(
+			zap.String("url", request.URL),
+			zap.String("method", strings.ToLower(method)),
+		),
+	}
+	h.ConfigRetryFrom(task)
+	h.SetTimeout(task.Timeout)
+	h.Action = h
+	return h
+}
+
+type HTTP struct {
+	common.Executable
+	common.Cancelable
+	common.Timeout
+	task *config.Task
+
+	method  string
+	request *config.HTTPRequest
+	headers *map[string]string
+	data    *any
+	expect  *expect.Rules
+	log     *zap.Logger
+}
+
+// Execute implements abstraction.Executable.
+func (h *HTTP) Do(ctx context.Context) (e error) {
+	ctx = populateVars(ctx, h.task)
+	log := h.log.With(
+		zap.Time("start", time.Now()),
+	)
+	defer func() {
+		err := recover()
+		if err != nil {
+			if err, ok := err.(error); ok {
+				log.Warn("recovering command execution from a fatal error", zap.Error(err))
+				e = fmt.Errorf("http task panicked: %w", err)
+				return
+			}
+			log.Warn("a non-error panic accord", zap.Any("error", err))
+			e = fmt.Errorf("http task panicked: %v", err)
+		}
+	}()
+
+	localCtx, cancel := h.ApplyTimeout(ctx)
+	h.SetCancel(cancel)
+	defer cancel()
+	defer func() {
+		e = common.TimeoutError(localCtx, e)
+	}()
+
+	transport, err := sharedTransport(h.task)
+	if err != nil {
+		log.Warn("cannot create the transport (pre-send)", zap.Error(err))
+		return err
+	}
+	req, err := h.newRequest(ctx, localCtx, log)
+	log.Debug("sending http request")
+	if err != nil {
+		log.Warn("cannot create the request (pre-send)", zap.Error(err))
+		return err
+	}
+	if err := authorize(localCtx, transport, h.task.Auth, req); err != nil {
+		log.Warn("cannot authorize the request (pre-send)", zap.Error(err))
+		return err
+	}
+	res, err := h.client(transport).Do(req)
+	var body []byte
+	if res != nil {
+		if res.Body != nil {
+			defer helpers.WarnOnErrIgnored(
+				log,
+				res.Body.Close,
+				"cannot close response body",
+			)
+		}
+		var captureErr error
+		if body, captureErr = captureHTTPResponse(common.ResultOf(ctx), res); captureErr != nil {
+			log.Warn("cannot read response body", zap.Error(captureErr))
+		}
+		if res.StatusCode == http.StatusUnauthorized {
+			forgetAuthorization(h.task.Auth, req)
+		}
+		log = log.With(zap.Int("status", res.StatusCode))
+		log.Info("received response with status", zap.String("status", res.Status))
+		if log.Level() >= zap.DebugLevel {
+			ans, respErr := logHTTPResponse(res)
+			log.Debug("fetched data", zap.String("response", ans), zap.Error(respErr))
+		}
+	}
+
+	if err != nil {
+		log.Warn("request failed", zap.Error(err))
+		return err
+	}
+	// expectations are checked against the whole body, the result only holds its head
+	if err := h.expect.Check(res.StatusCode, body); err != nil {
+		log.Warn("response did not satisfy expectations", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// newRequest renders the request using template data of ctx, the request itself is bound to reqCtx.
+func (h *HTTP) newRequest(ctx context.Context, reqCtx context.Context, log *zap.Logger) (*http.Request, error) {
+	address, err := url.Parse(command.Render(ctx, log, h.request.URL))
+	if err != nil {
+		return nil, err
+	}
+	if len(h.request.Query) != 0 {
+		query := address.Query()
+		for key, val := range h.request.Query {
+			query.Add(key, command.Render(ctx, log, val))
+		}
+		address.RawQuery = query.Encode()
+	}
+
+	var body io.Reader
+	var contentType string
+	switch {
+	case h.request.Body != "":
+		body = strings.NewReader(command.Render(ctx, log, h.request.Body))
+	case h.request.Form != nil:
+		form := url.Values{}
+		for key, val := range h.request.Form {
+			form.Add(key, command.Render(ctx, log, val))
+		}
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case h.data != nil && *h.data != nil:
+		data, err := json.Marshal(renderData(ctx, log, *h.data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal the given body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	req, err := http.NewRequestWithContext(reqCtx, h.method, address.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range renderHeaders(ctx, log, *h.headers) {
+		req.Header.Add(key, val)
+	}
+	switch {
+	case h.request.ContentType != "":
+		req.Header.Set("Content-Type", h.request.ContentType)
+	case contentType != "" && req.Header.Get("Content-Type") == "":
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+func (h *HTTP) client(transport http.RoundTripper) *http.Client {
+	maxRedirects := int(h.request.MaxRedirects)
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if h.request.Redirect == config.RedirectNone {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
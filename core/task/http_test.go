@@ -0,0 +1,43 @@
+package task
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
+)
+
+func TestHTTP_PanicFails(t *testing.T) {
+	task := &config.Task{Get: "http://127.0.0.1"}
+	exe, ok := NewGet(zap.NewNop(), task)
+	assert.True(t, ok)
+	get := exe.(*HTTP)
+	get.headers = nil
+
+	err := get.Do(t.Context())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "http task panicked")
+}
+
+func TestCaptureHTTPResponse_Truncates(t *testing.T) {
+	global.Put(common.MaxOutput(4))
+	t.Cleanup(func() {
+		global.Put(common.MaxOutput(0))
+	})
+	res := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("abcdefgh"))}
+	result := &common.Result{}
+	body, err := captureHTTPResponse(result, res)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdefgh", string(body))
+	assert.Equal(t, common.TruncatedMark+"abcd", result.Response)
+	body, err = io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdefgh", string(body))
+}
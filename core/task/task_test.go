@@ -2,12 +2,23 @@ package task_test
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"go.uber.org/zap"
 
 	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/event"
+	"github.com/fmotalleb/crontab-go/core/global"
 	"github.com/fmotalleb/crontab-go/core/task"
 	"github.com/fmotalleb/crontab-go/ctxutils"
 )
@@ -73,3 +84,264 @@ func TestCompileTask_WithHooks(t *testing.T) {
 	exe := task.Build(ctx, zap.NewNop(), taskConfig)
 	assert.NotEqual(t, exe, nil)
 }
+
+func TestPostTask_RendersTemplates(t *testing.T) {
+	type captured struct {
+		path   string
+		header string
+		body   map[string]any
+	}
+	requests := make(chan captured, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := captured{path: r.URL.Path, header: r.Header.Get("X-Container")}
+		_ = json.NewDecoder(r.Body).Decode(&c.body)
+		requests <- c
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Post:       server.URL + "/hooks/{{ .Vars.target }}",
+		Headers:    map[string]string{"X-Container": "{{ .name }}"},
+		RetryDelay: time.Millisecond,
+		Data: map[string]any{
+			"container": "{{ .name }}",
+			"tags":      []any{"static", "{{ .Vars.target }}"},
+			"count":     3,
+		},
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	ctx = context.WithValue(ctx, ctxutils.EventData, event.NewMetaData("test", map[string]any{"name": "db"}))
+	ctx = context.WithValue(ctx, ctxutils.Vars, map[string]string{"target": "restart"})
+	assert.NoError(t, exe.Execute(ctx))
+
+	req := <-requests
+	assert.Equal(t, "/hooks/restart", req.path)
+	assert.Equal(t, "db", req.header)
+	assert.Equal(t, map[string]any{
+		"container": "db",
+		"tags":      []any{"static", "restart"},
+		"count":     float64(3),
+	}, req.body)
+}
+
+func TestCompileTask_HTTPTask(t *testing.T) {
+	ctx := t.Context()
+	ctx = context.WithValue(ctx, ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		HTTP: &config.HTTPRequest{URL: "test", Method: "delete"},
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	assert.NotEqual(t, exe, nil)
+}
+
+func TestHTTPTask_RawBody(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:         server.URL + "/upload?keep=1",
+			Method:      "put",
+			Query:       map[string]string{"name": "{{ .Vars.name }}"},
+			Body:        "payload of {{ .Vars.name }}",
+			ContentType: "text/plain",
+		},
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	ctx = context.WithValue(ctx, ctxutils.Vars, map[string]string{"name": "backup"})
+	assert.NoError(t, exe.Execute(ctx))
+
+	req := <-requests
+	assert.Equal(t, http.MethodPut, req.Method)
+	assert.Equal(t, "/upload", req.URL.Path)
+	assert.Equal(t, "1", req.URL.Query().Get("keep"))
+	assert.Equal(t, "backup", req.URL.Query().Get("name"))
+	assert.Equal(t, "text/plain", req.Header.Get("Content-Type"))
+	assert.Equal(t, "payload of backup", <-bodies)
+}
+
+func TestHTTPTask_Form(t *testing.T) {
+	forms := make(chan url.Values, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		forms <- r.PostForm
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:    server.URL,
+			Method: http.MethodPatch,
+			Form:   map[string]string{"state": "on"},
+		},
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	assert.NoError(t, exe.Execute(ctx))
+	assert.Equal(t, "on", (<-forms).Get("state"))
+}
+
+func TestHTTPTask_RedirectNone(t *testing.T) {
+	hits := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- r.URL.Path
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		HTTP: &config.HTTPRequest{
+			URL:      server.URL + "/old",
+			Redirect: config.RedirectNone,
+		},
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	ctx, res := common.WithResult(ctx)
+	assert.NoError(t, exe.Execute(ctx))
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Equal(t, 1, len(hits))
+}
+
+func TestGetTask_FailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Get:        server.URL,
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	err := exe.Execute(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code 500")
+}
+
+func TestGetTask_Expect(t *testing.T) {
+	calls := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"healthy":false}`))
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Get: server.URL,
+		Expect: &config.HTTPExpect{
+			Status: []string{"2xx"},
+			JSON:   []string{"$.healthy == true"},
+		},
+		Retries:       1,
+		RetryDelay:    time.Millisecond,
+		RetryModifier: "const",
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	err := exe.Execute(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "$.healthy == true")
+	assert.Equal(t, 2, calls.Load())
+}
+
+func TestGetTask_ExpectLargeBody(t *testing.T) {
+	global.Put(common.MaxOutput(16))
+	t.Cleanup(func() {
+		global.Put(common.MaxOutput(0))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"padding":"` + strings.Repeat("x", 64) + `","healthy":true}`))
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Get: server.URL,
+		Expect: &config.HTTPExpect{
+			Body: `^\{.*\}$`,
+			JSON: []string{"$.healthy == true"},
+		},
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	assert.NoError(t, exe.Execute(ctx))
+}
+
+func TestTask_When(t *testing.T) {
+	calls := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+	t.Setenv("CRONTAB_TEST_STAGE", "prod")
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Get:        server.URL,
+		RetryDelay: time.Millisecond,
+		Vars:       map[string]string{"prefix": "web-"},
+		When:       `and (hasPrefix .name .Vars.prefix) (eq .Env.CRONTAB_TEST_STAGE "prod")`,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+
+	skipped := context.WithValue(ctx, ctxutils.EventData, event.NewMetaData("docker", map[string]any{"name": "db-1"}))
+	assert.IsError(t, exe.Execute(skipped), common.ErrSkipped)
+	assert.Equal(t, 0, calls.Load())
+
+	matched := context.WithValue(ctx, ctxutils.EventData, event.NewMetaData("docker", map[string]any{"name": "web-1"}))
+	assert.NoError(t, exe.Execute(matched))
+	assert.Equal(t, 1, calls.Load())
+}
+
+func TestCommandTask_TimeoutKillsProcessGroup(t *testing.T) {
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		// the shell and its child ignore the stop signal and keep the output pipe open
+		Command:    `trap "" TERM; sleep 30`,
+		Timeout:    100 * time.Millisecond,
+		StopGrace:  200 * time.Millisecond,
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+
+	ctx, res := common.WithResult(ctx)
+	start := time.Now()
+	err := exe.Execute(ctx)
+	assert.IsError(t, err, common.ErrTimedOut)
+	assert.True(t, res.TimedOut)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestGetTask_IgnoresData(t *testing.T) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(t.Context(), ctxutils.JobKey, "test_job")
+	taskConfig := config.Task{
+		Get:        server.URL,
+		Data:       map[string]any{"ignored": true},
+		RetryDelay: time.Millisecond,
+	}
+	exe := task.Build(ctx, zap.NewNop(), taskConfig)
+	assert.NoError(t, exe.Execute(ctx))
+	assert.Equal(t, "", <-bodies)
+}
@@ -27,5 +27,5 @@ func Build(ctx context.Context, log *zap.Logger, cfg config.Task) abstraction.Ex
 		onFail = append(onFail, Build(ctx, log, d))
 	}
 	exe.SetFailHooks(ctx, onFail)
-	return exe
+	return newConditional(log, &cfg, exe)
 }
@@ -0,0 +1,125 @@
+package task
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+var (
+	transportsLock sync.Mutex
+	transports     = make(map[string]*cachedTransport)
+)
+
+// cachedTransport is a shared transport along with the state of tls files it was built from.
+type cachedTransport struct {
+	files     string
+	transport *http.Transport
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// sharedTransport returns the transport matching tls and proxy settings of the task,
+// tasks with identical settings share a single transport (and its connection pool).
+// The transport is rebuilt once any of the tls files is modified (e.g. a rotated certificate).
+func sharedTransport(task *config.Task) (http.RoundTripper, error) {
+	if task.TLS == nil && task.Proxy == "" {
+		return http.DefaultTransport, nil
+	}
+	key := fmt.Sprintf("%+v|%s", task.TLS, task.Proxy)
+	files := ""
+	if task.TLS != nil {
+		key = fmt.Sprintf("%+v|%s", *task.TLS, task.Proxy)
+		files = fileStates(task.TLS.CA, task.TLS.Cert, task.TLS.Key)
+	}
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	cached, ok := transports[key]
+	if ok && cached.files == files {
+		return cached.transport, nil
+	}
+	transport, err := newTransport(task.TLS, task.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	transports[key] = &cachedTransport{files: files, transport: transport}
+	return transport, nil
+}
+
+// fileStates describes modification time and size of given files, missing files are described as such.
+func fileStates(paths ...string) string {
+	var states strings.Builder
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&states, "%s:missing|", path)
+			continue
+		}
+		fmt.Fprintf(&states, "%s:%d:%d|", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return states.String()
+}
+
+func newTransport(cfg *config.HTTPTLS, proxy string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg == nil {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.MinVersion != "" {
+		version, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown tls min-version `%s`", cfg.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if cfg.CA != "" {
+		pem, err := os.ReadFile(cfg.CA)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ca bundle does not contain any valid certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,158 @@
+package task
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/config"
+)
+
+// writeCertificate generates a self-signed certificate valid for localhost and writes it alongside its key into dir.
+func writeCertificate(t *testing.T, dir string, name string) (tls.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	return cert, certFile, keyFile
+}
+
+func TestSharedTransport_Default(t *testing.T) {
+	transport, err := sharedTransport(&config.Task{})
+	assert.NoError(t, err)
+	assert.True(t, http.DefaultTransport == transport)
+}
+
+func TestSharedTransport_Reused(t *testing.T) {
+	first, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{MinVersion: "1.2"}})
+	assert.NoError(t, err)
+	second, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{MinVersion: "1.2"}})
+	assert.NoError(t, err)
+	other, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{MinVersion: "1.3"}})
+	assert.NoError(t, err)
+	assert.True(t, first == second)
+	assert.False(t, first == other)
+}
+
+func TestSharedTransport_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, caFile, _ := writeCertificate(t, dir, "server")
+	clientCert, certFile, keyFile := writeCertificate(t, dir, "client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	transport, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{CA: caFile}})
+	assert.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Error(t, err)
+
+	transport, err = sharedTransport(&config.Task{TLS: &config.HTTPTLS{CA: caFile, Cert: certFile, Key: keyFile}})
+	assert.NoError(t, err)
+	res, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestSharedTransport_ReloadsRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := writeCertificate(t, dir, "client")
+	task := &config.Task{TLS: &config.HTTPTLS{Cert: certFile, Key: keyFile}}
+
+	first, err := sharedTransport(task)
+	assert.NoError(t, err)
+	second, err := sharedTransport(task)
+	assert.NoError(t, err)
+	assert.True(t, first == second)
+
+	rotated, _, _ := writeCertificate(t, dir, "client")
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	third, err := sharedTransport(task)
+	assert.NoError(t, err)
+	assert.False(t, first == third)
+	assert.Equal(t, rotated.Certificate, third.(*http.Transport).TLSClientConfig.Certificates[0].Certificate)
+}
+
+func TestSharedTransport_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	_, err := (&http.Client{Transport: http.DefaultTransport}).Get(server.URL)
+	assert.Error(t, err)
+
+	transport, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{InsecureSkipVerify: true}})
+	assert.NoError(t, err)
+	res, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+}
+
+func TestSharedTransport_Proxy(t *testing.T) {
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+	}))
+	defer proxy.Close()
+
+	transport, err := sharedTransport(&config.Task{Proxy: proxy.URL})
+	assert.NoError(t, err)
+	res, err := (&http.Client{Transport: transport}).Get("http://internal.example/ping")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "http://internal.example/ping", <-proxied)
+}
+
+func TestSharedTransport_MissingCA(t *testing.T) {
+	_, err := sharedTransport(&config.Task{TLS: &config.HTTPTLS{CA: filepath.Join(t.TempDir(), "missing.pem")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package task
+
+import (
+	"context"
+	"maps"
+
+	"go.uber.org/zap"
+
+	"github.com/fmotalleb/crontab-go/abstraction"
+	"github.com/fmotalleb/crontab-go/config"
+	"github.com/fmotalleb/crontab-go/core/cmd_connection/command"
+	"github.com/fmotalleb/crontab-go/core/common"
+	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/when"
+)
+
+// conditional skips the wrapped task (alongside its hooks) when its `when` condition does not hold.
+type conditional struct {
+	abstraction.Executable
+	task *config.Task
+	cond *when.Condition
+	log  *zap.Logger
+}
+
+func newConditional(log *zap.Logger, cfg *config.Task, exe abstraction.Executable) abstraction.Executable {
+	cond, err := when.Compile(cfg.When)
+	if err != nil {
+		log.Panic("invalid when condition", zap.Error(err))
+	}
+	if cond == nil {
+		return exe
+	}
+	global.RegisterCounter(
+		global.SkipMetricName,
+		global.SkipMetricHelp,
+		exe.GetMeta(),
+	)
+	return &conditional{
+		Executable: exe,
+		task:       cfg,
+		cond:       cond,
+		log:        log.With(zap.Stringer("when", cond)),
+	}
+}
+
+// Execute implements abstraction.Executable.
+func (c *conditional) Execute(ctx context.Context) error {
+	data := command.TemplateData(populateVars(ctx, c.task))
+	env := when.Environ()
+	maps.Copy(env, c.task.Env)
+	data[when.EnvKey] = env
+	ok, err := c.cond.Eval(data)
+	if err != nil {
+		c.log.Warn("failed to evaluate when condition, skipping the task", zap.Error(err))
+	}
+	if !ok {
+		c.log.Debug("when condition did not hold, skipping the task")
+		global.IncMetric(
+			global.SkipMetricName,
+			global.SkipMetricHelp,
+			c.GetMeta(),
+		)
+		return common.ErrSkipped
+	}
+	return c.Executable.Execute(ctx)
+}
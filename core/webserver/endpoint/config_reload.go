@@ -0,0 +1,34 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/fmotalleb/crontab-go/core/jobs"
+)
+
+// Reloader reloads the configuration and reports the jobs affected by it.
+type Reloader func() (jobs.Changes, error)
+
+type ConfigReloadEndpoint struct {
+	reload Reloader
+}
+
+func NewConfigReloadEndpoint(reload Reloader) *ConfigReloadEndpoint {
+	return &ConfigReloadEndpoint{
+		reload: reload,
+	}
+}
+
+// Endpoint reloads the configuration, an invalid configuration is rejected and the running one is kept.
+func (cr *ConfigReloadEndpoint) Endpoint(c echo.Context) error {
+	if cr.reload == nil {
+		return c.String(http.StatusNotFound, "config reload is not available")
+	}
+	changes, err := cr.reload()
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+	return c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,45 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/fmotalleb/crontab-go/core/global"
+	"github.com/fmotalleb/crontab-go/core/history"
+)
+
+const defaultRunsLimit = 20
+
+type JobRunsEndpoint struct{}
+
+func NewJobRunsEndpoint() *JobRunsEndpoint {
+	return &JobRunsEndpoint{}
+}
+
+// Endpoint lists recorded runs of the job, newest first.
+// `status` (success, failure) filters runs and `limit` (defaults to 20, 0 for all) limits the amount of them.
+func (jr *JobRunsEndpoint) Endpoint(c echo.Context) error {
+	store := global.Get[*history.Store]()
+	if store == nil {
+		return c.String(http.StatusNotFound, "run history is disabled, please set `state_dir` to enable it")
+	}
+	query := history.Query{
+		Status: history.Status(c.QueryParam("status")),
+		Limit:  defaultRunsLimit,
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("invalid limit: '%s'", limit))
+		}
+		query.Limit = l
+	}
+	runs, err := store.Runs(c.Param("name"), query)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, runs)
+}
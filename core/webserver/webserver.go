@@ -27,6 +27,7 @@ type WebServer struct {
 	port         uint
 	log          *zap.Logger
 	serveMetrics bool
+	reload       endpoint.Reloader
 }
 
 func NewWebServer(ctx context.Context,
@@ -45,6 +46,12 @@ func NewWebServer(ctx context.Context,
 	}
 }
 
+// WithReloader enables the config reload endpoint.
+func (s *WebServer) WithReloader(reload endpoint.Reloader) *WebServer {
+	s.reload = reload
+	return s
+}
+
 func (s *WebServer) Serve() {
 	engine := echo.New()
 
@@ -102,6 +109,16 @@ func (s *WebServer) Serve() {
 		"/events/:event/emit",
 		ed.Endpoint,
 	)
+	jr := endpoint.NewJobRunsEndpoint()
+	engine.GET(
+		"/api/jobs/:name/runs",
+		jr.Endpoint,
+	)
+	cr := endpoint.NewConfigReloadEndpoint(s.reload)
+	engine.POST(
+		"/api/config/reload",
+		cr.Endpoint,
+	)
 	if s.serveMetrics {
 		engine.GET("/metrics", func(c echo.Context) error {
 			promhttp.Handler().ServeHTTP(c.Response().Writer, c.Request())
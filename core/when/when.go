@@ -0,0 +1,91 @@
+// Package when evaluates `when` conditions of tasks and events.
+//
+// A condition is a go template expression (e.g. `eq .name "web"`), braces are optional.
+// The condition holds unless it renders to an empty string, `false`, `0`, `no` or `<no value>`.
+package when
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// EnvKey is the key of the environment variables in the data of conditions.
+const EnvKey = "Env"
+
+var funcs = template.FuncMap{
+	"match":     regexp.MatchString,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+}
+
+var falsy = []string{"", "false", "0", "no", "<no value>"}
+
+// Condition is a compiled `when` expression.
+type Condition struct {
+	expr string
+	tmpl *template.Template
+}
+
+// Compile parses the expression, an empty expression results in a nil condition which always holds.
+func Compile(expr string) (*Condition, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+	src := expr
+	if !strings.Contains(src, "{{") {
+		src = "{{ " + src + " }}"
+	}
+	tmpl, err := template.New("when").Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid when expression `%s`: %w", expr, err)
+	}
+	return &Condition{expr: expr, tmpl: tmpl}, nil
+}
+
+// String returns the source expression.
+func (c *Condition) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.expr
+}
+
+// Eval evaluates the condition against data, environment variables are exposed as `.Env` unless data already has it.
+func (c *Condition) Eval(data map[string]any) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	if _, ok := data[EnvKey]; !ok {
+		data[EnvKey] = Environ()
+	}
+	out := new(bytes.Buffer)
+	if err := c.tmpl.Execute(out, data); err != nil {
+		return false, fmt.Errorf("failed to evaluate when expression `%s`: %w", c.expr, err)
+	}
+	result := strings.ToLower(strings.TrimSpace(out.String()))
+	for _, f := range falsy {
+		if result == f {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Environ returns environment variables of the process as a map.
+func Environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
@@ -0,0 +1,57 @@
+package when_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/fmotalleb/crontab-go/core/when"
+)
+
+func TestCondition_Eval(t *testing.T) {
+	t.Setenv("CRONTAB_WHEN_STAGE", "prod")
+	data := func() map[string]any {
+		return map[string]any{
+			"name": "web-1",
+			"Vars": map[string]string{"count": "3"},
+		}
+	}
+	cases := map[string]bool{
+		`eq .name "web-1"`: true,
+		`eq .name "db"`:    false,
+		`{{ if hasPrefix .name "web" }}yes{{ end }}`:         true,
+		`match "^db-" .name`:                                 false,
+		`and (match "^web-" .name) (ne .Vars.count "0")`:     true,
+		`eq .Env.CRONTAB_WHEN_STAGE "prod"`:                  true,
+		`.missing`:                                           false,
+		`.Vars.count`:                                        true,
+		`or (eq .name "db") (contains (lower .name) "WEB" )`: false,
+	}
+	for expr, expected := range cases {
+		cond, err := when.Compile(expr)
+		assert.NoError(t, err, expr)
+		ok, err := cond.Eval(data())
+		assert.NoError(t, err, expr)
+		assert.Equal(t, expected, ok, expr)
+	}
+}
+
+func TestCondition_Empty(t *testing.T) {
+	cond, err := when.Compile("  ")
+	assert.NoError(t, err)
+	ok, err := cond.Eval(map[string]any{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCondition_Invalid(t *testing.T) {
+	_, err := when.Compile(`eq .name (`)
+	assert.Error(t, err)
+	_, err = when.Compile(`unknownFunc .name`)
+	assert.Error(t, err)
+
+	cond, err := when.Compile(`eq .name 1`)
+	assert.NoError(t, err)
+	_, err = cond.Eval(map[string]any{"name": "web"})
+	assert.Error(t, err)
+}
@@ -12,4 +12,6 @@ var (
 	EventData      = ContextKey("event-data")
 	Environments   = ContextKey("cmd-environments")
 	Vars           = ContextKey("cmd-vars")
+	TaskResult     = ContextKey("task-result")
+	Result         = ContextKey("trigger-result")
 )